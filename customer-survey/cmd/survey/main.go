@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"customer-survey/internal/survey"
 	"customer-survey/internal/ui"
+	"customer-survey/pkg/startup"
+	"errors"
 	"log"
+	"os"
+	"os/signal"
 	"runtime"
 	"syscall"
+	"time"
 )
 
 func hideConsole() {
@@ -26,12 +33,60 @@ func hideConsole() {
 	}
 }
 
+// replayTimeout bounds how long the background replay of queued submissions may run
+const replayTimeout = 2 * time.Minute
+
 func main() {
+	// customer-survey.exe validate-config [file...] checks configuration and exits
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(survey.RunValidateConfig(os.Args[2:]))
+	}
+
 	// Hide console window before launching UI
 	hideConsole()
 
-	// Launch native Windows desktop UI
-	if err := ui.RunDesktopUI(); err != nil {
-		log.Fatalf("Failed to start application: %v", err)
+	// Only one survey per user: a second launch (an RDS reconnect, a second
+	// shell) leaves the prompt to the first
+	release, err := startup.AcquireInstance()
+	var running *startup.RunningError
+	if errors.As(err, &running) {
+		log.Printf("Exiting: %v", running)
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking for a running survey: %v", err)
+		release = func() error { return nil }
+	}
+
+	// Report configuration mistakes now rather than on the first submission
+	survey.ValidateConfig()
+
+	// Re-send anything a previous run could not deliver
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+		defer cancel()
+		if result, err := survey.ReplayPending(ctx); err != nil {
+			log.Printf("Replay of queued submissions stopped: %v", err)
+		} else if result.Replayed() > 0 {
+			log.Printf("Replayed queued submissions: %s", result)
+		}
+	}()
+
+	// Launch native Windows desktop UI; it returns once the page is done
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	outcome, err := ui.RunDesktopUI(ctx)
+	stop()
+	release()
+
+	// The page is answered once a response is queued; finish sending it
+	wait, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	if survey.WaitDeliveries(wait) != nil {
+		log.Printf("Submissions still sending at exit stay queued for the next launch")
+	}
+	cancel()
+	if err != nil {
+		log.Printf("Survey server stopped: %v", err)
+		os.Exit(1)
 	}
+	os.Exit(outcome.ExitCode())
 }
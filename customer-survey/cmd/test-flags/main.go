@@ -1,17 +1,18 @@
 package main
 
 import (
+	"customer-survey/pkg/appdata"
 	"customer-survey/pkg/startup"
 	"fmt"
 	"os"
-	"path/filepath"
+	"time"
 )
 
 func main() {
 	fmt.Println("=== Testing Startup Package Functions (No UI) ===")
 
 	// Show AppData location
-	appDataDir := startup.GetAppDataDir()
+	appDataDir := appdata.Dir()
 	fmt.Printf("AppData Directory: %s\n\n", appDataDir)
 
 	// Test 1: Check initial state
@@ -29,10 +30,9 @@ func main() {
 	if err := startup.MarkSurveyDone(); err != nil {
 		fmt.Printf("  Error: %v\n", err)
 	} else {
-		fmt.Println("  ✓ done.flag created")
-		flagPath := filepath.Join(appDataDir, "done.flag")
-		if content, err := os.ReadFile(flagPath); err == nil {
-			fmt.Printf("  Content: %s\n", string(content))
+		fmt.Println("  ✓ Survey marked as done")
+		if content, err := os.ReadFile(startup.StatePath()); err == nil {
+			fmt.Printf("  %s:\n%s\n", startup.StateFile, string(content))
 		}
 	}
 
@@ -50,7 +50,7 @@ func main() {
 	if err := startup.MarkNoThanks(); err != nil {
 		fmt.Printf("  Error: %v\n", err)
 	} else {
-		fmt.Println("  ✓ nothanks.flag created")
+		fmt.Println("  ✓ No Thanks recorded")
 	}
 
 	fmt.Printf("  IsNoThanks: %v\n", startup.IsNoThanks())
@@ -66,10 +66,9 @@ func main() {
 	if err := startup.MarkRemindLater(); err != nil {
 		fmt.Printf("  Error: %v\n", err)
 	} else {
-		fmt.Println("  ✓ remind.txt created")
-		remindPath := filepath.Join(appDataDir, "remind.txt")
-		if content, err := os.ReadFile(remindPath); err == nil {
-			fmt.Printf("  Remind until: %s\n", string(content))
+		fmt.Println("  ✓ Reminder recorded")
+		if st, err := startup.LoadStore(); err == nil && st.Survey.RemindAt != nil {
+			fmt.Printf("  Remind until: %s\n", st.Survey.RemindAt.Format(time.RFC3339))
 		}
 	}
 
@@ -80,9 +79,9 @@ func main() {
 	fmt.Printf("  Status: %s\n\n", startup.GetStatus())
 
 	// Final cleanup
-	fmt.Println("[Cleanup] Removing test files")
+	fmt.Println("[Cleanup] Clearing test state")
 	startup.ResetAll()
-	fmt.Println("  ✓ All test files removed")
+	fmt.Println("  ✓ All test state cleared")
 
 	fmt.Println("\n=== All Tests Complete (No UI Launched) ===")
 }
@@ -1,6 +1,8 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function GetDefinition():Promise<Record<string, any>>;
+
 export function GetStartupStatus():Promise<string>;
 
 export function HandleNoThanks():Promise<Record<string, any>>;
@@ -9,4 +11,6 @@ export function HandleRemindMeLater():Promise<Record<string, any>>;
 
 export function ResetStartupSettings():Promise<Record<string, any>>;
 
-export function SubmitSurvey(arg1:string,arg2:number,arg3:number,arg4:number,arg5:string):Promise<Record<string, any>>;
+export function SubmitSurvey(arg1:Record<string, any>):Promise<Record<string, any>>;
+
+export function VisibleQuestions(arg1:Record<string, any>):Promise<Array<string>>;
@@ -2,6 +2,10 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function GetDefinition() {
+  return window['go']['main']['App']['GetDefinition']();
+}
+
 export function GetStartupStatus() {
   return window['go']['main']['App']['GetStartupStatus']();
 }
@@ -18,6 +22,10 @@ export function ResetStartupSettings() {
   return window['go']['main']['App']['ResetStartupSettings']();
 }
 
-export function SubmitSurvey(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['SubmitSurvey'](arg1, arg2, arg3, arg4, arg5);
+export function SubmitSurvey(arg1) {
+  return window['go']['main']['App']['SubmitSurvey'](arg1);
+}
+
+export function VisibleQuestions(arg1) {
+  return window['go']['main']['App']['VisibleQuestions'](arg1);
 }
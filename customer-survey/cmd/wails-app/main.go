@@ -1,19 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"customer-survey/pkg/campaign"
+	"customer-survey/pkg/config"
+	"customer-survey/pkg/definition"
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/outbox"
+	"customer-survey/pkg/sink"
 	"customer-survey/pkg/startup"
+	"customer-survey/pkg/sysinfo"
 	"embed"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/wailsapp/wails/v2"
@@ -31,172 +36,219 @@ var assets embed.FS
 //go:embed config.json
 var defaultConfigData []byte
 
-// Survey represents the survey data structure
-type Survey struct {
-	SurveyResponse    string `json:"survey_response"`
-	ServerPerformance int    `json:"server_performance"`
-	TechnicalSupport  int    `json:"technical_support"`
-	OverallSupport    int    `json:"overall_support"`
-	Note              string `json:"note"`
-	Timestamp         string `json:"timestamp"`
-	Username          string `json:"username"`
-	MachineName       string `json:"machine_name"`
-}
-
-// Config represents the Zoho configuration
-type Config struct {
-	ZohoWebhookURL string `json:"zoho_webhook_url"`
-}
-
 // App struct
 type App struct {
-	ctx    context.Context
-	config *Config
+	ctx      context.Context
+	config   *config.Config
+	survey   *definition.Definition
+	campaign *campaign.Campaign // nil when no campaigns are configured
+	state    startup.State      // done / no-thanks / remind-later flags of the campaign
+	system   sysinfo.Attributes // for the survey's display conditions
+	outbox   *outbox.Outbox
+	delivery sink.Sink // nil when nothing is configured; data stays in the outbox
+
+	deliveries sync.WaitGroup // deliveries running in the background
 }
 
-// NewApp creates a new App application struct
-func NewApp() *App {
-	// Load config
-	config := loadConfig()
+// replayTimeout bounds how long the replay of queued submissions may run
+const replayTimeout = 60 * time.Second
+
+// deliveryTimeout bounds the delivery of one answer, retries included
+const deliveryTimeout = 2 * time.Minute
+
+// NewApp creates a new App application struct. camp is the campaign being
+// asked, or nil without campaigns.
+func NewApp(cfg *config.Config, survey *definition.Definition, camp *campaign.Campaign, box *outbox.Outbox, delivery sink.Sink) *App {
 	return &App{
-		config: config,
+		config:   cfg,
+		survey:   survey,
+		campaign: camp,
+		state:    startup.For(campaignID(camp)).WithPolicy(cfg.Policy()),
+		system:   sysinfo.Collect(),
+		outbox:   box,
+		delivery: delivery,
 	}
 }
 
-// loadConfig loads the configuration from config.json
-func loadConfig() *Config {
-	// Get executable directory FIRST - this is critical for deployed apps
-	exePath, err := os.Executable()
-	exeDir := ""
-	if err == nil {
-		exeDir = filepath.Dir(exePath)
-	}
-
-	// PRIORITY ORDER: Search executable directory FIRST, then fallback to other locations
-	possiblePaths := []string{}
-
-	// 1. Executable directory paths (HIGHEST PRIORITY - for deployed apps)
-	if exeDir != "" {
-		possiblePaths = append(possiblePaths,
-			filepath.Join(exeDir, "config.json"),
-			filepath.Join(exeDir, "configs", "config.json"),
-		)
-	}
-
-	// 2. Current working directory (fallback for development)
-	possiblePaths = append(possiblePaths,
-		"config.json",
-		filepath.Join("configs", "config.json"),
-		filepath.Join("..", "..", "config.json"),
-		filepath.Join("..", "..", "configs", "config.json"),
-	)
-
-	log.Printf("Looking for config.json in:")
-	for i, path := range possiblePaths {
-		absPath, _ := filepath.Abs(path)
-		log.Printf("  %d. %s", i+1, absPath)
-	}
-
-	var configData []byte
-	var foundPath string
-
-	for _, path := range possiblePaths {
-		configData, err = ioutil.ReadFile(path)
-		if err == nil {
-			foundPath = path
-			absFoundPath, _ := filepath.Abs(path)
-			log.Printf("✓ Config loaded successfully from: %s", absFoundPath)
-			break
+// buildSink creates the delivery sink from the "sinks" list in the configuration.
+// Without a list, "delivery_mode" picks either the webhook URL (default) or
+// Zoho Creator via OAuth. Returns nil when nothing usable is configured.
+func buildSink(cfg *config.Config) sink.Sink {
+	policy := cfg.Retry.Policy()
+	if len(cfg.Sinks) > 0 {
+		s, err := sink.Build(cfg.SinkConfigs(), policy)
+		if err != nil {
+			log.Printf("ERROR: Invalid sinks in config.json: %v", err)
+			log.Printf("Data will be saved LOCALLY ONLY until the sinks are fixed!")
+			return nil
 		}
+		log.Printf("✓ Delivering via %s", s.Name())
+		return s
 	}
 
-	// If not found on disk, use embedded config and create file in hidden location
-	if foundPath == "" {
-		log.Printf("⚠ config.json not found on disk - using embedded default")
+	timeout := cfg.Timeout(30 * time.Second)
+	if strings.EqualFold(cfg.DeliveryMode, sink.ModeCreator) {
+		s, err := sink.ForMode(cfg.DeliveryMode, "", true, cfg.ZohoCreator, timeout, policy)
+		if err != nil {
+			log.Printf("ERROR: Zoho Creator delivery is not usable: %v", err)
+			log.Printf("Data will be saved LOCALLY ONLY until the Creator credentials are fixed!")
+			return nil
+		}
+		log.Printf("✓ Delivering via Zoho Creator (OAuth)")
+		return s
+	}
 
-		// Use embedded config data
-		if len(defaultConfigData) > 0 {
-			configData = defaultConfigData
-			log.Printf("✓ Using embedded config (built into exe)")
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	// The Wails UI has always sent Good/Okay/Bad labels rather than numbers
+	return sink.NewWebhook("zoho-flow", cfg.WebhookURL, true, timeout, policy)
+}
 
-			// Create config.json in hidden AppData location
-			localAppData := os.Getenv("LOCALAPPDATA")
-			if localAppData == "" {
-				localAppData = os.Getenv("APPDATA")
-			}
+// loadConfig resolves the layered configuration (embedded config.json <
+// machine-wide file < per-user file < environment) and logs where each
+// value came from
+func loadConfig() *config.Loaded {
+	loaded, err := config.Load(defaultConfigData)
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		log.Printf("File may be corrupted or have invalid JSON syntax")
+	}
 
-			if localAppData != "" {
-				// Create .ace-survey directory if it doesn't exist
-				configDir := filepath.Join(localAppData, ".ace-survey")
-				if err := os.MkdirAll(configDir, 0755); err != nil {
-					log.Printf("⚠ Could not create config directory: %v", err)
-				} else {
-					configPath := filepath.Join(configDir, "config.json")
-					if err := ioutil.WriteFile(configPath, defaultConfigData, 0644); err != nil {
-						log.Printf("⚠ Could not save config to AppData: %v", err)
-					} else {
-						log.Printf("✓ Saved config to: %s", configPath)
-						log.Printf("  (Hidden location in AppData - embedded config will be used)")
-					}
-				}
-			}
-		} else {
-			log.Printf("╔════════════════════════════════════════════════════════╗")
-			log.Printf("║ ERROR: No config.json found and no embedded config!   ║")
-			log.Printf("╚════════════════════════════════════════════════════════╝")
-			log.Printf("CRITICAL: Without config.json, webhook will NOT work!")
-			log.Printf("")
-			log.Printf("To fix this issue:")
-			log.Printf("  1. Create config.json in the SAME folder as the .exe")
-			if exeDir != "" {
-				log.Printf("     Location: %s", filepath.Join(exeDir, "config.json"))
-			}
-			log.Printf("  2. Add this content:")
-			log.Printf("     {")
-			log.Printf("       \"zoho_webhook_url\": \"your-webhook-url-here\"")
-			log.Printf("     }")
-			log.Printf("")
-			log.Printf("Data will be saved LOCALLY ONLY until config.json is added!")
-			log.Printf("")
-			return &Config{
-				ZohoWebhookURL: "",
-			}
-		}
+	log.Printf("Configuration sources (lowest to highest: default, machine, user, env):")
+	for _, line := range loaded.Report() {
+		log.Printf("  %s", line)
 	}
+	cfg := &loaded.Config
 
-	var config Config
-	if err := json.Unmarshal(configData, &config); err != nil {
-		log.Printf("ERROR: Could not parse config.json: %v", err)
-		log.Printf("File may be corrupted or have invalid JSON syntax")
-		return &Config{
-			ZohoWebhookURL: "",
+	if err := loaded.Validate(); err != nil {
+		log.Printf("╔════════════════════════════════════════════════════════╗")
+		log.Printf("║ ERROR: Configuration has problems!                    ║")
+		log.Printf("╚════════════════════════════════════════════════════════╝")
+		for _, problem := range err.(*config.ValidationError).Problems {
+			log.Printf("  - %s", problem)
 		}
+		log.Printf("Run: survey.exe validate-config <config.json> to check a file")
 	}
 
 	// Validate webhook URL
-	if config.ZohoWebhookURL == "" {
+	if strings.EqualFold(cfg.DeliveryMode, sink.ModeCreator) {
+		log.Printf("✓ delivery_mode is creator - webhook_url is not used")
+	} else if cfg.WebhookURL == "" {
 		log.Printf("╔════════════════════════════════════════════════════════╗")
-		log.Printf("║ WARNING: zoho_webhook_url is EMPTY in config.json!    ║")
+		log.Printf("║ WARNING: webhook_url is not configured anywhere!      ║")
 		log.Printf("╚════════════════════════════════════════════════════════╝")
+		log.Printf("Searched:")
+		for i, path := range loaded.Searched {
+			absPath, _ := filepath.Abs(path)
+			log.Printf("  %d. %s", i+1, absPath)
+		}
+		log.Printf("To fix this issue, create config.json in the SAME folder as the .exe with:")
+		log.Printf("     {")
+		log.Printf("       \"webhook_url\": \"your-webhook-url-here\"")
+		log.Printf("     }")
+		log.Printf("or set the ZOHO_WEBHOOK_URL environment variable.")
 		log.Printf("Data will be saved LOCALLY ONLY - no data sent to Zoho!")
-	} else if !isValidURL(config.ZohoWebhookURL) {
+	} else if !isValidURL(cfg.WebhookURL) {
 		log.Printf("╔════════════════════════════════════════════════════════╗")
 		log.Printf("║ ERROR: Invalid webhook URL in config.json!            ║")
 		log.Printf("╚════════════════════════════════════════════════════════╝")
 		log.Printf("URL must start with http:// or https://")
-		log.Printf("Current value: %s", config.ZohoWebhookURL)
-		config.ZohoWebhookURL = "" // Clear invalid URL
+		log.Printf("Current value: %s", cfg.WebhookURL)
+		cfg.WebhookURL = "" // Clear invalid URL
 	} else {
 		log.Printf("✓ Webhook URL configured and validated")
-		log.Printf("  URL: %s", config.ZohoWebhookURL)
+		log.Printf("  URL: %s", cfg.WebhookURL)
+	}
+
+	return loaded
+}
+
+// loadDefinition resolves the survey the form asks. A broken definition
+// file is reported and the default survey is asked instead.
+func loadDefinition(loaded *config.Loaded) *definition.Definition {
+	def, err := loaded.Definition()
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		log.Printf("Asking the default survey instead")
+	}
+	if src, ok := loaded.Source("survey_definition"); ok {
+		log.Printf("✓ Survey %s v%d from %s", def.ID, def.Version, src)
+	} else {
+		log.Printf("✓ Survey %s v%d (built in)", def.ID, def.Version)
+	}
+	log.Printf("System attributes for display conditions: %v", sysinfo.Collect())
+	return def
+}
+
+// selectCampaign picks the campaign to ask. ok is false when campaigns are
+// configured but none is running for this user and survey version; reason
+// then says why.
+func selectCampaign(campaigns []campaign.Campaign, def *definition.Definition) (camp *campaign.Campaign, ok bool, reason string) {
+	if len(campaigns) == 0 {
+		return nil, true, ""
+	}
+	target := currentTarget()
+	now := time.Now()
+	if camp := campaign.Select(campaigns, now, target, def.Version); camp != nil {
+		return camp, true, ""
+	}
+	var reasons []string
+	for i := range campaigns {
+		reasons = append(reasons, campaigns[i].Why(now, target, def.Version))
+	}
+	return nil, false, "no active campaign: " + strings.Join(reasons, "; ")
+}
+
+// currentTarget identifies this user and machine for campaign audiences
+func currentTarget() campaign.Target {
+	username := os.Getenv("USERNAME")
+	if username == "" {
+		username = os.Getenv("USER")
 	}
+	machineName := os.Getenv("COMPUTERNAME")
+	if machineName == "" {
+		machineName, _ = os.Hostname()
+	}
+	return campaign.Target{User: username, Computer: machineName, System: sysinfo.Collect()}
+}
+
+// campaignID returns the id of camp, or "" without a campaign
+func campaignID(camp *campaign.Campaign) string {
+	if camp == nil {
+		return ""
+	}
+	return camp.ID
+}
 
-	return &config
+// stamp records the survey, version and campaign on a response and gives it its submission id
+func (a *App) stamp(resp *model.SurveyResponse) {
+	a.survey.Stamp(resp)
+	resp.Campaign = campaignID(a.campaign)
+	resp.Stamp()
+}
+
+// GetDefinition returns the survey definition the form is rendered from
+func (a *App) GetDefinition() map[string]interface{} {
+	return map[string]interface{}{"success": true, "definition": a.survey}
+}
+
+// VisibleQuestions returns the keys of the questions to show for the
+// answers given so far, evaluating the survey's display conditions
+func (a *App) VisibleQuestions(answers map[string]interface{}) []string {
+	return a.survey.Visible(answers, a.system)
 }
 
 // GetStartupStatus returns the current startup status for debugging
 func (a *App) GetStartupStatus() string {
-	return startup.GetStatus()
+	status := a.state.Evaluate(time.Now()).String()
+	if a.campaign != nil {
+		status = fmt.Sprintf("Campaign %s, %s: %s", a.campaign.ID, a.campaign.SampleStatus(currentTarget()), status)
+	}
+	if machine := startup.MachinePolicyStatus(); machine != "" {
+		status += " (" + machine + ")"
+	}
+	return status
 }
 
 // ResetStartupSettings resets all startup flags (for testing/debugging)
@@ -223,15 +275,23 @@ func (a *App) startup(ctx context.Context) {
 	}()
 }
 
-// HandleRemindMeLater saves reminder settings and closes the app
+// HandleRemindMeLater snoozes the survey for the next step of the snooze
+// schedule and reports how long, or that it was the last snooze allowed
 func (a *App) HandleRemindMeLater() map[string]interface{} {
 	log.Printf("\n========== REMIND ME LATER ==========")
 
-	if err := startup.MarkRemindLater(); err != nil {
+	snooze, err := a.state.RemindLater()
+	if err != nil {
 		log.Printf("Error saving Remind Me Later: %v", err)
 		return map[string]interface{}{"success": false, "error": err.Error()}
 	}
-	log.Printf("✓ Reminder set for 7 days")
+	note := fmt.Sprintf("User clicked 'Remind Me Later' (%d in a row) - will be shown again in %s", snooze.Number, snooze.Wait())
+	if snooze.Last {
+		note = fmt.Sprintf("User clicked 'Remind Me Later' (%d in a row) - survey will not be shown again", snooze.Number)
+		log.Printf("✓ Snooze %d of %d: survey will not be shown again", snooze.Number, a.state.Policy.MaxConsecutiveSnoozes)
+	} else {
+		log.Printf("✓ Reminder set for %s (snooze %d in a row, until %s)", snooze.Wait(), snooze.Number, snooze.Until.Format(time.RFC3339))
+	}
 
 	// Submit to Zoho Sheets
 	username := os.Getenv("USERNAME")
@@ -243,51 +303,51 @@ func (a *App) HandleRemindMeLater() map[string]interface{} {
 		machineName, _ = os.Hostname()
 	}
 
-	reminderData := &Survey{
-		SurveyResponse:    "Remind Me Later",
-		ServerPerformance: 0,
-		TechnicalSupport:  0,
-		OverallSupport:    0,
-		Note:              "User clicked 'Remind Me Later' - will be shown again in 7 days",
-		Timestamp:         time.Now().Format(time.RFC3339),
-		Username:          username,
-		MachineName:       machineName,
+	reminderData := model.SurveyResponse{
+		SurveyResponse: model.DesktopRemindLater,
+		Answers:        model.Answers{model.KeyNote: note},
+		UserName:       username,
+		ServerName:     machineName,
+		Snoozes:        snooze.Number,
+	}
+	if !snooze.Last {
+		reminderData.RemindAt = &snooze.Until
 	}
+	a.stamp(&reminderData)
 
 	log.Printf("Survey Response: %s", reminderData.SurveyResponse)
 	log.Printf("Username: %s", username)
 	log.Printf("Machine Name: %s", machineName)
-	log.Printf("Note: %s", reminderData.Note)
-
-	// Save backup locally
-	if err := saveBackup(reminderData); err != nil {
-		log.Printf("Error saving reminder backup: %v", err)
-	}
-
-	// Submit to Zoho webhook if configured
-	webhookURL := a.config.ZohoWebhookURL
-	if webhookURL != "" {
-		log.Printf("Attempting to submit Remind Me Later to webhook...")
-		if err := submitToZoho(webhookURL, reminderData); err != nil {
-			log.Printf("ERROR: Failed to submit reminder to Zoho: %v", err)
-			log.Printf("Data is saved locally. Check config.json webhook URL.")
-		} else {
-			log.Printf("✓ Reminder event submitted to Zoho Sheets")
-		}
+	log.Printf("Note: %s", reminderData.Answers.String(model.KeyNote))
+
+	// Queue in the local outbox before attempting delivery
+	rec := a.queue(reminderData)
+
+	// Submit to the configured sinks
+	if a.delivery != nil {
+		log.Printf("Attempting to submit Remind Me Later via %s...", a.delivery.Name())
+		a.deliver(rec, reminderData, func(err error) {
+			if err != nil {
+				log.Printf("ERROR: Failed to submit reminder to Zoho: %v", err)
+				log.Printf("Data is saved locally. Check config.json webhook URL.")
+			} else {
+				log.Printf("✓ Reminder event submitted to Zoho Sheets")
+			}
+		})
 	} else {
 		log.Printf("ERROR: No webhook URL configured in config.json")
 		log.Printf("Data is saved locally only - webhook will not be called")
 	}
 
 	log.Printf("========================================\n")
-	return map[string]interface{}{"success": true}
+	return map[string]interface{}{"success": true, "remind_in": snooze.Wait(), "last": snooze.Last}
 }
 
 // HandleNoThanks saves no thanks settings and closes the app
 func (a *App) HandleNoThanks() map[string]interface{} {
 	log.Printf("\n========== NO THANKS ==========")
 
-	if err := startup.MarkNoThanks(); err != nil {
+	if err := a.state.MarkNoThanks(); err != nil {
 		log.Printf("Error saving No Thanks: %v", err)
 		return map[string]interface{}{"success": false, "error": err.Error()}
 	}
@@ -303,37 +363,33 @@ func (a *App) HandleNoThanks() map[string]interface{} {
 		machineName, _ = os.Hostname()
 	}
 
-	noThanksData := &Survey{
-		SurveyResponse:    "No Thanks",
-		ServerPerformance: 0,
-		TechnicalSupport:  0,
-		OverallSupport:    0,
-		Note:              "User clicked 'No Thanks' - survey will not be shown again",
-		Timestamp:         time.Now().Format(time.RFC3339),
-		Username:          username,
-		MachineName:       machineName,
+	noThanksData := model.SurveyResponse{
+		SurveyResponse: model.DesktopDeclined,
+		Answers:        model.Answers{model.KeyNote: "User clicked 'No Thanks' - survey will not be shown again"},
+		UserName:       username,
+		ServerName:     machineName,
 	}
+	a.stamp(&noThanksData)
 
 	log.Printf("Survey Response: %s", noThanksData.SurveyResponse)
 	log.Printf("Username: %s", username)
 	log.Printf("Machine Name: %s", machineName)
-	log.Printf("Note: %s", noThanksData.Note)
-
-	// Save backup locally
-	if err := saveBackup(noThanksData); err != nil {
-		log.Printf("Error saving no thanks backup: %v", err)
-	}
-
-	// Submit to Zoho webhook if configured
-	webhookURL := a.config.ZohoWebhookURL
-	if webhookURL != "" {
-		log.Printf("Attempting to submit No Thanks to webhook...")
-		if err := submitToZoho(webhookURL, noThanksData); err != nil {
-			log.Printf("ERROR: Failed to submit no thanks to Zoho: %v", err)
-			log.Printf("Data is saved locally. Check config.json webhook URL.")
-		} else {
-			log.Printf("✓ No Thanks event submitted to Zoho Sheets")
-		}
+	log.Printf("Note: %s", noThanksData.Answers.String(model.KeyNote))
+
+	// Queue in the local outbox before attempting delivery
+	rec := a.queue(noThanksData)
+
+	// Submit to the configured sinks
+	if a.delivery != nil {
+		log.Printf("Attempting to submit No Thanks via %s...", a.delivery.Name())
+		a.deliver(rec, noThanksData, func(err error) {
+			if err != nil {
+				log.Printf("ERROR: Failed to submit no thanks to Zoho: %v", err)
+				log.Printf("Data is saved locally. Check config.json webhook URL.")
+			} else {
+				log.Printf("✓ No Thanks event submitted to Zoho Sheets")
+			}
+		})
 	} else {
 		log.Printf("ERROR: No webhook URL configured in config.json")
 		log.Printf("Data is saved locally only - webhook will not be called")
@@ -343,8 +399,9 @@ func (a *App) HandleNoThanks() map[string]interface{} {
 	return map[string]interface{}{"success": true}
 }
 
-// SubmitSurvey submits the survey data
-func (a *App) SubmitSurvey(surveyResponse string, serverPerformance, technicalSupport, overallSupport int, note string) map[string]interface{} {
+// SubmitSurvey submits a completed survey; answers are keyed by question
+// key of the survey definition
+func (a *App) SubmitSurvey(answers map[string]interface{}) map[string]interface{} {
 	// Get user and machine info
 	username := os.Getenv("USERNAME")
 	if username == "" {
@@ -355,75 +412,86 @@ func (a *App) SubmitSurvey(surveyResponse string, serverPerformance, technicalSu
 		machineName, _ = os.Hostname()
 	}
 
-	surveyData := &Survey{
-		SurveyResponse:    "Complete",
-		ServerPerformance: serverPerformance,
-		TechnicalSupport:  technicalSupport,
-		OverallSupport:    overallSupport,
-		Note:              note,
-		Timestamp:         time.Now().Format(time.RFC3339),
-		Username:          username,
-		MachineName:       machineName,
+	// Check the answers against the survey before anything is stored
+	checked, err := a.survey.Check(answers, a.system)
+	if err != nil {
+		log.Printf("Rejected survey submission: %v", err)
+		return map[string]interface{}{
+			"success":  false,
+			"error":    "Some answers are not valid",
+			"problems": err.(*definition.AnswerError).Problems,
+		}
+	}
+
+	surveyData := model.SurveyResponse{
+		SurveyResponse: model.DesktopCompleted,
+		Answers:        checked,
+		UserName:       username,
+		ServerName:     machineName,
 	}
+	a.stamp(&surveyData)
 
 	// Log the submission with clear formatting
 	log.Printf("\n========== SURVEY SUBMISSION ==========")
-	log.Printf("Survey Response: %s", surveyResponse)
-	log.Printf("Server Performance Rating: %d", serverPerformance)
-	log.Printf("Technical Support Rating: %d", technicalSupport)
-	log.Printf("Overall Support Rating: %d", overallSupport)
-	log.Printf("Feedback/Note: %s", note)
-	log.Printf("Timestamp: %s", surveyData.Timestamp)
+	log.Printf("Survey Response: %s", surveyData.SurveyResponse)
+	log.Printf("Survey: %s v%d", surveyData.SurveyID, surveyData.SurveyVersion)
+	if surveyData.Campaign != "" {
+		log.Printf("Campaign: %s", surveyData.Campaign)
+	}
+	for _, q := range a.survey.Questions {
+		log.Printf("%s: %s", q.Prompt, surveyData.Answers.String(q.Key))
+	}
+	log.Printf("Submission ID: %s", surveyData.SubmissionID)
+	log.Printf("Answered At: %s", surveyData.AnsweredAt.Format(time.RFC3339))
 	log.Printf("Username: %s", username)
 	log.Printf("Machine Name: %s", machineName)
 	log.Printf("========================================\n")
 
-	// Save backup locally
-	if err := saveBackup(surveyData); err != nil {
-		log.Printf("Error saving backup: %v", err)
-	}
+	// Queue in the local outbox before attempting delivery
+	rec := a.queue(surveyData)
 
-	// Mark survey as done so it won't show again for this user
-	if err := startup.MarkSurveyDone(); err != nil {
+	// Mark survey as done so it won't show again for this user (in this campaign)
+	if err := a.state.MarkSurveyDone(); err != nil {
 		log.Printf("Error marking survey as done: %v", err)
 	} else {
 		log.Printf("✓ Survey marked as completed for this user")
 	}
 
-	// Submit to Zoho webhook if configured
-	webhookURL := a.config.ZohoWebhookURL
-	if webhookURL == "" {
+	// Submit to the configured sinks
+	if a.delivery == nil {
 		log.Printf("╔════════════════════════════════════════════════════════╗")
 		log.Printf("║ WEBHOOK DISABLED: No URL configured                   ║")
 		log.Printf("╚════════════════════════════════════════════════════════╝")
-		log.Printf("✓ Data saved to local backup: %s\\Acesurvey.txt", os.Getenv("LOCALAPPDATA"))
+		log.Printf("✓ Data saved to local outbox: %s", a.outbox.Path())
 		log.Printf("")
 		log.Printf("To enable Zoho integration:")
 		log.Printf("  1. Create config.json in same directory as exe")
 		log.Printf("  2. Add: {\"zoho_webhook_url\": \"your-webhook-url\"}")
 	} else {
 		log.Printf("🌐 Attempting webhook submission...")
-		log.Printf("   Target: %s", webhookURL)
-		if err := submitToZoho(webhookURL, surveyData); err != nil {
-			log.Printf("╔════════════════════════════════════════════════════════╗")
-			log.Printf("║ ❌ WEBHOOK SUBMISSION FAILED                          ║")
-			log.Printf("╚════════════════════════════════════════════════════════╝")
-			log.Printf("Error: %v", err)
-			log.Printf("")
-			log.Printf("✓ Data IS saved locally to: %s\\Acesurvey.txt", os.Getenv("LOCALAPPDATA"))
-			log.Printf("")
-			log.Printf("Troubleshooting:")
-			log.Printf("  1. Verify config.json exists next to exe")
-			log.Printf("  2. Check zoho_webhook_url is not empty")
-			log.Printf("  3. Test webhook URL in browser or Postman")
-			log.Printf("  4. Verify Zoho Flow is active")
-			log.Printf("  5. Check internet connection and firewall")
-		} else {
-			log.Printf("╔════════════════════════════════════════════════════════╗")
-			log.Printf("║ ✅ WEBHOOK SUBMISSION SUCCESSFUL                      ║")
-			log.Printf("╚════════════════════════════════════════════════════════╝")
-			log.Printf("Data sent to Zoho Sheets successfully!")
-		}
+		log.Printf("   Target: %s", a.delivery.Name())
+		a.deliver(rec, surveyData, func(err error) {
+			if err != nil {
+				log.Printf("╔════════════════════════════════════════════════════════╗")
+				log.Printf("║ ❌ WEBHOOK SUBMISSION FAILED                          ║")
+				log.Printf("╚════════════════════════════════════════════════════════╝")
+				log.Printf("Error: %v", err)
+				log.Printf("")
+				log.Printf("✓ Data IS saved locally to: %s", a.outbox.Path())
+				log.Printf("")
+				log.Printf("Troubleshooting:")
+				log.Printf("  1. Verify config.json exists next to exe")
+				log.Printf("  2. Check zoho_webhook_url is not empty")
+				log.Printf("  3. Test webhook URL in browser or Postman")
+				log.Printf("  4. Verify Zoho Flow is active")
+				log.Printf("  5. Check internet connection and firewall")
+			} else {
+				log.Printf("╔════════════════════════════════════════════════════════╗")
+				log.Printf("║ ✅ WEBHOOK SUBMISSION SUCCESSFUL                      ║")
+				log.Printf("╚════════════════════════════════════════════════════════╝")
+				log.Printf("Data sent to Zoho Sheets successfully!")
+			}
+		})
 	}
 
 	return map[string]interface{}{
@@ -431,39 +499,98 @@ func (a *App) SubmitSurvey(surveyResponse string, serverPerformance, technicalSu
 	}
 }
 
-// saveBackup saves survey data to local file
-func saveBackup(data *Survey) error {
-	localAppData := os.Getenv("LOCALAPPDATA")
-	if localAppData == "" {
-		localAppData = os.Getenv("TEMP")
+// queue stores the survey in the local outbox so it survives a failed submission.
+// Returns nil if the outbox could not be written.
+func (a *App) queue(data model.SurveyResponse) *outbox.Record {
+	rec, err := a.outbox.Add(data)
+	if err != nil {
+		log.Printf("Error saving to outbox: %v", err)
+		return nil
 	}
+	log.Printf("Queued in outbox: %s", a.outbox.Path())
+	return rec
+}
 
-	backupFile := filepath.Join(localAppData, "Acesurvey.txt")
+// deliver sends data to the configured sinks in the background, so the
+// window does not wait for their retries, and records the outcome in the
+// outbox; report is called with it. A record some sinks accepted is only
+// retried with the others.
+func (a *App) deliver(rec *outbox.Record, data model.SurveyResponse, report func(error)) {
+	a.deliveries.Add(1)
+	go func() {
+		defer a.deliveries.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		defer cancel()
+
+		send := sink.Sender(a.delivery)
+		if rec == nil {
+			_, err := send(ctx, data, nil)
+			report(err)
+			return
+		}
+		sendErr, err := a.outbox.Deliver(ctx, rec, send)
+		if err != nil {
+			log.Printf("Error updating outbox record %s: %v", rec.ID, err)
+		}
+		report(sendErr)
+	}()
+}
 
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal survey data: %w", err)
+// waitDeliveries waits up to timeout for the deliveries still running.
+// Whatever is not delivered by then stays in the outbox for the next launch.
+func (a *App) waitDeliveries(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		a.deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("Submissions still sending at exit stay queued for the next launch")
 	}
+}
 
-	// Append to file
-	f, err := os.OpenFile(backupFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
+// replayOutbox re-sends every pending outbox record to the configured sinks.
+// Records are marked delivered only when every required sink accepts them.
+func replayOutbox(box *outbox.Outbox, delivery sink.Sink) {
+	if delivery == nil {
+		log.Printf("Skipping replay of queued submissions: no webhook URL configured")
+		return
 	}
-	defer f.Close()
 
-	if _, err := f.Write(jsonData); err != nil {
-		return fmt.Errorf("failed to write to backup file: %w", err)
+	ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	defer cancel()
+
+	result, err := box.Replay(ctx, sink.Sender(delivery))
+	if err != nil {
+		log.Printf("Replay of queued submissions stopped: %v", err)
+		return
 	}
-	if _, err := f.WriteString("\n---\n"); err != nil {
-		return fmt.Errorf("failed to write separator: %w", err)
+	if result.Replayed() > 0 {
+		log.Printf("Replayed queued submissions: %s", result)
 	}
+}
 
-	log.Printf("Backup saved to: %s", backupFile)
-	return nil
+// waitForPromptTime waits out the logon delay of the "prompt_timing" rules
+// and reports whether the prompt may appear now
+func waitForPromptTime(timing startup.Timing, logon startup.Logon) bool {
+	for {
+		d := timing.Check(time.Now(), logon)
+		switch d.Reason {
+		case startup.ReasonShow:
+			return true
+		case startup.ReasonLogonDelay:
+			log.Printf("%s", d)
+			time.Sleep(time.Until(d.Until))
+		default:
+			log.Printf("Survey prompt suppressed: %s", d)
+			return false
+		}
+	}
 }
 
-// submitToZoho submits data to Zoho webhook
+// isValidURL reports whether the webhook URL looks usable
 func isValidURL(urlStr string) bool {
 	if urlStr == "" {
 		return false
@@ -474,103 +601,10 @@ func isValidURL(urlStr string) bool {
 	return true
 }
 
-func submitToZoho(webhookURL string, data *Survey) error {
-	// Create payload with field names that match Zoho Flow expectations
-	// These field names match exactly what the Zoho Flow is configured to receive
-	// Convert ratings to strings for Zoho Flow
-	ratingToString := func(r int) string {
-		switch r {
-		case 3:
-			return "Good"
-		case 2:
-			return "Okay"
-		case 1:
-			return "Bad"
-		default:
-			return "Unknown"
-		}
-	}
-	payload := map[string]interface{}{
-		"timestamp":          data.Timestamp,
-		"machine_name":       data.MachineName, // Zoho Flow expects: machine_name
-		"username":           data.Username,
-		"server_performance": ratingToString(data.ServerPerformance),
-		"technical_support":  ratingToString(data.TechnicalSupport),
-		"overall_support":    ratingToString(data.OverallSupport),
-		"note":               data.Note, // Zoho Flow expects: note
-		"survey_response":    data.SurveyResponse,
-	}
-
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal data: %w", err)
-	}
-
-	log.Printf("Submitting to webhook: %s", webhookURL)
-	log.Printf("Payload JSON: %s", string(jsonData))
-	log.Printf("Payload size: %d bytes", len(jsonData))
-
-	// Validate webhook URL
-	if webhookURL == "" {
-		return fmt.Errorf("webhook URL is empty")
-	}
-	if !isValidURL(webhookURL) {
-		return fmt.Errorf("webhook URL is invalid: %s", webhookURL)
-	}
-
-	// Create request with proper headers for Zoho
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("ERROR: Failed to create request: %v", err)
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set critical headers - Zoho is sensitive to these
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "CustomerSurvey/2.0")
-	req.Header.Set("Accept", "application/json")
-
-	log.Printf("Request headers: %v", req.Header)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("ERROR: HTTP request failed: %v", err)
-		log.Printf("Network troubleshooting:")
-		log.Printf("  - Check internet connection")
-		log.Printf("  - Check firewall/proxy settings")
-		log.Printf("  - Try webhook URL in browser")
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Failed to read response body: %v", err)
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	log.Printf("Webhook response status: %d", resp.StatusCode)
-	if len(body) > 0 {
-		log.Printf("Webhook response body: %s", string(body))
-	}
-
-	// Success if we get 200-299 status code
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		log.Printf("✓ Successfully submitted to Zoho webhook (HTTP %d)", resp.StatusCode)
-		return nil
-	}
-
-	// Any other status is an error
-	log.Printf("ERROR: Webhook rejected the request with status %d", resp.StatusCode)
-	log.Printf("Response: %s", string(body))
-	return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
-}
-
 func main() {
+	// Started from the Startup folder, so this is about when the user logged on
+	started := time.Now()
+
 	// Parse command-line flags
 	resetFlag := flag.Bool("reset", false, "Reset survey settings and show prompt")
 	helpFlag := flag.Bool("help", false, "Show help message")
@@ -583,8 +617,28 @@ func main() {
 		fmt.Println("Options:")
 		fmt.Println("  -reset   Reset survey settings and show prompt again")
 		fmt.Println("  -help    Show this help message")
+		fmt.Println("Commands:")
+		fmt.Println("  validate-config [file...]   Check config files (or the loaded configuration) and exit")
+		return
+	}
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "validate-config" {
+		os.Exit(config.RunValidate(defaultConfigData, args[1:], os.Stdout))
+	}
+
+	// Only one survey per user: a second launch (an RDS reconnect, a second
+	// shell) leaves the prompt to the first
+	release, instanceErr := startup.AcquireInstance()
+	var running *startup.RunningError
+	if errors.As(instanceErr, &running) {
+		log.Printf("Exiting: %v", running)
 		return
 	}
+	if instanceErr != nil {
+		log.Printf("Error checking for a running survey: %v", instanceErr)
+	} else {
+		defer release()
+	}
 
 	// Reset settings if requested
 	if *resetFlag {
@@ -597,30 +651,63 @@ func main() {
 		// Continue to show survey after reset
 	}
 
+	loaded := loadConfig()
+	cfg := &loaded.Config
+	box := outbox.Default()
+	delivery := buildSink(cfg)
+	def := loadDefinition(loaded)
+	logon, logonErr := startup.RecordLogon(started)
+	if logonErr != nil {
+		log.Printf("Error recording logon: %v", logonErr)
+	}
+
+	// Without a running campaign for this user there is nothing to ask
+	camp, ok, reason := selectCampaign(cfg.Campaigns, def)
+	if !ok {
+		log.Printf("Survey prompt suppressed: %s", reason)
+		replayOutbox(box, delivery)
+		return
+	}
+	if camp != nil {
+		log.Printf("✓ Asking campaign %s, %s", camp.ID, camp.SampleStatus(currentTarget()))
+	}
+	state := startup.For(campaignID(camp)).WithPolicy(cfg.Policy())
+
 	// Check if survey prompt should be shown (unless we just reset)
-	shouldShow := *resetFlag // Always show if reset was used
-	if !*resetFlag {
-		var err error
-		shouldShow, err = startup.ShouldShowSurvey()
-		if err != nil {
-			log.Printf("Error checking startup settings: %v", err)
-			shouldShow = true // Show by default if error
-		}
+	decision := state.Evaluate(time.Now())
+	shouldShow := (*resetFlag && !decision.Enforced) || decision.Show // Always show if reset was used, unless the machine policy says no
+	if decision.Err != nil && !*resetFlag {
+		log.Printf("Error checking startup settings: %v", decision.Err)
+		shouldShow = true // Show by default if error
 	}
 
 	// If user said "No Thanks" or within "Remind Me Later" window or already completed, exit silently
 	if !shouldShow {
-		status := startup.GetStatus()
-		log.Printf("Survey prompt suppressed: %s", status)
+		log.Printf("Survey prompt suppressed: %s", decision)
 		log.Printf("To reset and show the survey again, run: survey.exe -reset")
+
+		// Still deliver anything queued by an earlier run before exiting
+		replayOutbox(box, delivery)
+		return
+	}
+
+	// Keep the prompt out of the way of the logon itself (unless we just reset)
+	if !*resetFlag && !waitForPromptTime(cfg.Timing(), logon) {
+		replayOutbox(box, delivery)
 		return
 	}
 
 	log.Printf("✓ Showing survey prompt")
-	log.Printf("Startup status: %s", startup.GetStatus())
+	log.Printf("Startup status: %s", decision)
+	if err := state.RecordPrompt(); err != nil {
+		log.Printf("Error counting the prompt: %v", err)
+	}
+
+	// Deliver anything queued by an earlier run while the prompt is showing
+	go replayOutbox(box, delivery)
 
 	// Create an instance of the app structure
-	app := NewApp()
+	app := NewApp(cfg, def, camp, box, delivery)
 
 	// Set environment variables to optimize WebView2 memory usage BEFORE Wails init
 	// These flags reduce GPU memory, disable hardware acceleration, and minimize caching
@@ -685,4 +772,7 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// The window closes as soon as an answer is queued; finish sending it
+	app.waitDeliveries(deliveryTimeout)
 }
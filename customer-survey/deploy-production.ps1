@@ -189,10 +189,11 @@ function Verify-Installation {
     
     foreach ($user in $users) {
         $userAppData = "C:\Users\$($user.Name)\AppData\Roaming\CustomerSurvey"
-        if (Test-Path $userAppData) {
-            if (Test-Path "$userAppData\done.flag") { $doneCount++ }
-            if (Test-Path "$userAppData\nothanks.flag") { $noThanksCount++ }
-            if (Test-Path "$userAppData\remind.txt") { $remindCount++ }
+        if (Test-Path "$userAppData\state.json") {
+            $state = (Get-Content "$userAppData\state.json" -Raw | ConvertFrom-Json).survey
+            if ($state.done_at) { $doneCount++ }
+            if ($state.no_thanks_at) { $noThanksCount++ }
+            if ($state.remind_at) { $remindCount++ }
         }
     }
     
@@ -2,4 +2,7 @@ module customer-survey
 
 go 1.21
 
-require golang.org/x/sys v0.30.0
+require (
+	golang.org/x/sys v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,33 @@
+package survey
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"customer-survey/pkg/campaign"
+	"customer-survey/pkg/definition"
+	"customer-survey/pkg/sysinfo"
+)
+
+// Definition returns the survey every UI asks, from the survey definition
+// file when one is deployed. A broken definition file is logged to
+// webhook.log and the default survey is asked instead.
+func Definition() *definition.Definition {
+	d, err := loadConfig().Definition()
+	if err != nil {
+		_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | %v (asking the default survey)\n", time.Now().UTC().Format(time.RFC3339), err))
+	}
+	return d
+}
+
+// CampaignID returns the id of the running campaign that targets user on
+// this machine and asks def, or "" when there is none
+func CampaignID(def *definition.Definition, user string) string {
+	hostname, _ := os.Hostname()
+	target := campaign.Target{User: user, Computer: hostname, System: sysinfo.Collect()}
+	if c := campaign.Select(loadConfig().Campaigns, time.Now(), target, def.Version); c != nil {
+		return c.ID
+	}
+	return ""
+}
@@ -1,19 +1,21 @@
 package survey
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"customer-survey/pkg/config"
 	"customer-survey/pkg/model"
+	"customer-survey/pkg/outbox"
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/sink"
 )
 
 // getLogPath returns a hidden log path in AppData to keep desktop clean
@@ -42,256 +44,204 @@ func appendFile(path, data string) error {
 //
 //	go build -ldflags "-X 'customer-survey/internal/survey.DefaultWebhookURL=https://.../exec'"
 //
-// If empty, env var or config.json will be used.
-// Default to Zoho Flow webhook for direct sheet integration
+// It is the lowest-precedence layer of pkg/config: env and config.json win.
+// Default to Zoho Flow webhook for direct sheet integration.
+// Deployments using "delivery_mode": "creator" never read it and can build
+// with -X 'customer-survey/internal/survey.DefaultWebhookURL=' to drop the key.
 var DefaultWebhookURL = "https://flow.zoho.in/60006321785/flow/webhook/incoming?zapikey=1001.754e60b74ab20d6a1f255f55358ee47d.815d8c8feab82ae7a18f99777d41a05f&isdebug=false"
 
-type appConfig struct {
-	WebhookURL string `json:"webhook_url"`
-}
-
-// getWebhookURL resolves the webhook URL from (in priority order):
-// 1) Env var ZOHO_WEBHOOK_URL
-// 2) config.json next to the executable (or current working dir)
-// 3) Build-time default (DefaultWebhookURL)
-func getWebhookURL() string {
-	if v := os.Getenv("ZOHO_WEBHOOK_URL"); strings.TrimSpace(v) != "" {
-		return v
-	}
-	// Try config.json next to the executable
-	if exe, err := os.Executable(); err == nil {
-		cfgPath := filepath.Join(filepath.Dir(exe), "config.json")
-		if b, err := os.ReadFile(cfgPath); err == nil {
-			var cfg appConfig
-			if json.Unmarshal(b, &cfg) == nil && strings.TrimSpace(cfg.WebhookURL) != "" {
-				return cfg.WebhookURL
-			}
-		}
-	}
-	// Try config.json in current working directory
-	if b, err := os.ReadFile("config.json"); err == nil {
-		var cfg appConfig
-		if json.Unmarshal(b, &cfg) == nil && strings.TrimSpace(cfg.WebhookURL) != "" {
-			return cfg.WebhookURL
-		}
-	}
-	// Fallback to compile-time default
-	if strings.TrimSpace(DefaultWebhookURL) != "" {
-		return DefaultWebhookURL
-	}
-	// Nothing found — write a helpful diagnostic to webhook.log so packaged EXEs report clearly
-	// This helps identify when the exe is run from a different folder or the config file wasn't packaged next to the exe.
-	diag := []string{"webhook resolution failed; attempted sources:"}
-	diag = append(diag, " - env ZOHO_WEBHOOK_URL (empty)")
-	if exe, err := os.Executable(); err == nil {
-		cfgPath := filepath.Join(filepath.Dir(exe), "config.json")
-		diag = append(diag, fmt.Sprintf(" - config next to exe: %s (exists=%v)", cfgPath, fileExists(cfgPath)))
-	}
-	// cwd config
-	cwdCfg := "config.json"
-	diag = append(diag, fmt.Sprintf(" - cwd config: %s (exists=%v)", cwdCfg, fileExists(cwdCfg)))
-	if strings.TrimSpace(DefaultWebhookURL) != "" {
-		diag = append(diag, fmt.Sprintf(" - build-time DefaultWebhookURL present"))
+// configDefaults is the embedded defaults layer: DefaultWebhookURL, if set
+func configDefaults() []byte {
+	if strings.TrimSpace(DefaultWebhookURL) == "" {
+		return nil
 	}
-	_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | %s\n", time.Now().UTC().Format(time.RFC3339), strings.Join(diag, "; ")))
-	return ""
+	defaults, _ := json.Marshal(map[string]string{"webhook_url": DefaultWebhookURL})
+	return defaults
 }
 
-// fileExists returns true if the given path exists and is a file
-func fileExists(path string) bool {
-	if path == "" {
-		return false
-	}
-	if info, err := os.Stat(path); err == nil && !info.IsDir() {
-		return true
+// loadConfig resolves the configuration through pkg/config, with
+// DefaultWebhookURL as the embedded default. Files that fail to parse are
+// logged to webhook.log and skipped.
+func loadConfig() *config.Loaded {
+	cfg, err := config.Load(configDefaults())
+	if err != nil {
+		_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | %v\n", time.Now().UTC().Format(time.RFC3339), err))
 	}
-	return false
+	return cfg
 }
 
-// saveToLocalFile saves survey response to AppData when webhook fails
-func saveToLocalFile(resp model.SurveyResponse) error {
-	// Get the correct AppData\Local path
-	var filePath string
-
-	// Try LOCALAPPDATA first (points to AppData\Local)
-	if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
-		filePath = filepath.Join(localAppData, "Acesurvey.txt")
-	} else {
-		// Fallback to USERPROFILE\AppData\Local
-		userProfile := os.Getenv("USERPROFILE")
-		if userProfile == "" {
-			return fmt.Errorf("cannot determine user profile path")
-		}
-		filePath = filepath.Join(userProfile, "AppData", "Local", "Acesurvey.txt")
-	}
-
-	// Prepare the data as JSON with timestamp
-	entry := map[string]interface{}{
-		"timestamp":          time.Now().Format(time.RFC3339),
-		"server_name":        resp.ServerName,
-		"username":           resp.UserName,
-		"survey_response":    resp.SurveyResponse,
-		"server_performance": resp.ServerPerformance,
-		"technical_support":  resp.TechnicalSupport,
-		"overall_rating":     resp.OverallSupport,
-		"feedback":           resp.Note,
-	}
-
-	entryJSON, _ := json.MarshalIndent(entry, "", "  ")
-
-	// OVERWRITE file with latest entry (do not append)
-	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+// ValidateConfig checks the configuration strictly at startup and logs every
+// problem to webhook.log, so a broken config.json is noticed immediately
+// instead of through an empty sheet weeks later
+func ValidateConfig() error {
+	err := loadConfig().Validate()
 	if err != nil {
-		return fmt.Errorf("failed to open local backup file: %w", err)
+		log.Printf("Configuration problems: %v", err)
+		_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | CONFIG ERROR: %v\n", time.Now().UTC().Format(time.RFC3339), err))
 	}
-	defer f.Close()
+	return err
+}
 
-	// Write JSON entry with separator
-	_, err = f.WriteString(string(entryJSON) + "\n")
-	if err != nil {
-		return fmt.Errorf("failed to write to local backup file: %w", err)
-	}
+// RunValidateConfig implements the validate-config subcommand and returns
+// the process exit code
+func RunValidateConfig(args []string) int {
+	return config.RunValidate(configDefaults(), args, os.Stdout)
+}
 
-	return nil
+// EphemeralPort reports whether the browser UI should listen on a port
+// picked by the OS ("ephemeral_port")
+func EphemeralPort() bool {
+	return loadConfig().EphemeralPort
 }
 
-// SubmitSurvey sends the survey response to Zoho Flow webhook which saves to Zoho Sheet
+// SubmitSurvey queues the survey response in the local outbox and then sends it
+// to the configured sinks (by default the Zoho Flow webhook which saves to Zoho
+// Sheet). The outbox record is marked sent only when every required sink
+// accepts it; otherwise it stays pending on disk.
 func SubmitSurvey(ctx context.Context, resp model.SurveyResponse) error {
-	webhookLogPath := getLogPath("webhook.log")
+	// Callers should stamp at answer time; this only covers ones that did not
+	resp.Stamp()
+	return deliver(ctx, queue(resp), resp)
+}
 
-	// Log the resolved webhook target
-	wb := getWebhookURL()
-	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | resolved-webhook: %s\n", time.Now().UTC().Format(time.RFC3339), wb))
+// deliveryTimeout bounds a delivery QueueSurvey runs in the background
+const deliveryTimeout = 2 * time.Minute
 
-	if wb == "" {
-		return fmt.Errorf("no webhook URL configured")
-	}
+// deliveries tracks the sends QueueSurvey started; started holds their
+// submission IDs
+var (
+	deliveries sync.WaitGroup
+	started    sync.Map
+)
 
-	// Prepare the payload for Zoho Flow (keys aligned to Sheet/Flow mappings)
-	// Ensure all values are strings to avoid type mismatch issues in Zoho Flow/Sheets.
-	// Keys used by Flow action:
-	// - server_name
-	// - username
-	// - server_performance
-	// - technical_support
-	// - overall_rating
-	// - feedback
-	// - survey_response (optional)
-	// - timestamp (optional)
-	payload := map[string]interface{}{
-		"machine_name":       fmt.Sprintf("%v", resp.ServerName),        // Zoho Flow expects: machine_name
-		"username":           fmt.Sprintf("%v", resp.UserName),
-		"survey_response":    fmt.Sprintf("%v", resp.SurveyResponse),
-		"server_performance": resp.ServerPerformance,                    // Zoho Flow expects numeric value
-		"technical_support":  resp.TechnicalSupport,                    // Zoho Flow expects numeric value
-		"overall_support":    resp.OverallSupport,                      // Zoho Flow expects: overall_support
-		"note":               fmt.Sprintf("%v", resp.Note),             // Zoho Flow expects: note
-		"timestamp":          time.Now().Format(time.RFC3339),
+// QueueSurvey queues the survey response in the local outbox and sends it
+// in the background, so a UI can answer the user without waiting for the
+// sinks and their retries. It returns an error only when the response could
+// not be queued; it is then still sent, without a local copy. WaitDeliveries
+// waits for the sends before the process exits.
+//
+// A response queued again with the same submission ID, as a UI retrying
+// after a timeout sends it, is the same outbox record and is not sent twice.
+func QueueSurvey(resp model.SurveyResponse) error {
+	resp.Stamp()
+	rec := queue(resp)
+	if _, dup := started.LoadOrStore(resp.SubmissionID, true); dup {
+		return nil
 	}
-
-	payloadJSON, _ := json.Marshal(payload)
-	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | Zoho Flow payload: %s\n", time.Now().UTC().Format(time.RFC3339), string(payloadJSON)))
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	deliveries.Add(1)
+	go func() {
+		defer deliveries.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		defer cancel()
+		if err := deliver(ctx, rec, resp); err != nil {
+			log.Printf("error submitting survey %s: %v (kept in the local outbox)", resp.SubmissionID, err)
+		}
+	}()
+	if rec == nil {
+		return fmt.Errorf("survey %s could not be queued in the local outbox", resp.SubmissionID)
 	}
+	return nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wb, bytes.NewBuffer(payloadJSON))
-	if err != nil {
-		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR creating request: %v\n", time.Now().UTC().Format(time.RFC3339), err))
-		return err
+// WaitDeliveries waits until the sends started by QueueSurvey are done or
+// ctx ends. Responses not delivered by then are pending in the outbox and
+// replayed on the next launch.
+func WaitDeliveries(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	res, err := client.Do(req)
+// queue adds resp to the local outbox, returning nil if it could not be stored
+func queue(resp model.SurveyResponse) *outbox.Record {
+	webhookLogPath := getLogPath("webhook.log")
+	box := outbox.Default()
+	rec, err := box.Add(resp)
 	if err != nil {
-		errMsg := fmt.Sprintf("%s | ERROR sending to Zoho Flow: %v\n", time.Now().UTC().Format(time.RFC3339), err)
-		_ = appendFile(webhookLogPath, errMsg)
-		log.Printf("[zoho-flow] Error: %v. Saving to local backup...", err)
-
-		// Save to local backup on network/webhook failure
-		if backupErr := saveToLocalFile(resp); backupErr != nil {
-			_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR saving backup: %v\n", time.Now().UTC().Format(time.RFC3339), backupErr))
-			log.Printf("[backup] Failed to save backup: %v", backupErr)
-		} else {
-			_ = appendFile(webhookLogPath, fmt.Sprintf("%s | SUCCESS: Backed up to local file\n", time.Now().UTC().Format(time.RFC3339)))
-			log.Printf("[backup] Saved to local backup file")
-		}
-
-		return err
-	}
-	defer res.Body.Close()
-
-	body, _ := io.ReadAll(res.Body)
-
-	if res.StatusCode >= 200 && res.StatusCode < 300 {
-		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | SUCCESS: Zoho Flow response status: %d\n", time.Now().UTC().Format(time.RFC3339), res.StatusCode))
-		log.Printf("[zoho-flow] Successfully submitted to Zoho Sheet via Flow")
-
-		// Also save to local backup for redundancy (even on success)
-		if backupErr := saveToLocalFile(resp); backupErr != nil {
-			log.Printf("[backup] Note: could not save backup copy: %v", backupErr)
-		}
-
+		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR queueing to outbox: %v\n", time.Now().UTC().Format(time.RFC3339), err))
+		log.Printf("[outbox] Failed to queue submission: %v", err)
 		return nil
 	}
+	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | queued to outbox: %s (%s)\n", time.Now().UTC().Format(time.RFC3339), rec.ID, box.Path()))
+	return rec
+}
 
-	// Log error response
-	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR: Zoho Flow response status: %d body: %s\n", time.Now().UTC().Format(time.RFC3339), res.StatusCode, string(body)))
-	log.Printf("[zoho-flow] Error response: status=%d body=%s", res.StatusCode, string(body))
-
-	if res.StatusCode >= 400 {
-		// Save to local backup on HTTP error
-		if backupErr := saveToLocalFile(resp); backupErr != nil {
-			_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR saving backup: %v\n", time.Now().UTC().Format(time.RFC3339), backupErr))
-			log.Printf("[backup] Failed to save backup: %v", backupErr)
-		} else {
-			_ = appendFile(webhookLogPath, fmt.Sprintf("%s | SUCCESS: Backed up to local file (HTTP %d)\n", time.Now().UTC().Format(time.RFC3339), res.StatusCode))
-			log.Printf("[backup] Saved to local backup file (HTTP %d)", res.StatusCode)
-		}
-
-		return fmt.Errorf("zoho flow returned %d: %s", res.StatusCode, string(body))
+// deliver sends resp and records the outcome on rec, if it was queued
+func deliver(ctx context.Context, rec *outbox.Record, resp model.SurveyResponse) error {
+	if rec == nil {
+		_, sendErr := send(ctx, resp, nil)
+		return sendErr
 	}
+	sendErr, err := outbox.Default().Deliver(ctx, rec, send)
+	if err != nil {
+		log.Printf("[outbox] Failed to update record %s: %v", rec.ID, err)
+	}
+	return sendErr
+}
 
-	return nil
+// ReplayPending re-sends every submission still pending in the local outbox
+// to the configured sinks. Records are marked delivered only when accepted.
+func ReplayPending(ctx context.Context) (outbox.ReplayResult, error) {
+	if _, err := getSink(); err != nil {
+		return outbox.ReplayResult{}, err
+	}
+	return outbox.Default().Replay(ctx, send)
 }
 
-// Removed legacy chromedp-based Zoho Survey automation to minimize binary size and dependencies.
+// getSink builds the delivery sink from the "sinks" list in the configuration.
+// Without a list, "delivery_mode" picks a single sink: the resolved webhook
+// URL (default) or Zoho Creator via OAuth.
+func getSink() (sink.Sink, error) {
+	cfg := loadConfig()
+	policy := cfg.Retry.Policy()
+	if len(cfg.Sinks) > 0 {
+		return sink.Build(cfg.SinkConfigs(), policy)
+	}
 
-// submitViaZohoOAuth submits survey data using secure OAuth authentication to Zoho Creator
-func submitViaZohoOAuth(ctx context.Context, resp model.SurveyResponse, config *ZohoConfig) error {
-	// Create Zoho Auth manager
-	zohoAuth := NewZohoAuth(config)
+	timeout := cfg.Timeout(10 * time.Second)
+	if strings.EqualFold(cfg.DeliveryMode, sink.ModeCreator) {
+		return sink.ForMode(cfg.DeliveryMode, "", false, cfg.ZohoCreator, timeout, policy)
+	}
 
-	// Prepare data for Zoho Creator
-	data := map[string]interface{}{
-		"Server_Name":         resp.ServerName,
-		"User_Name":           resp.UserName,
-		"Survey_Response":     resp.SurveyResponse,
-		"Server_Performance":  resp.ServerPerformance,
-		"Technical_Support":   resp.TechnicalSupport,
-		"Overall_Support":     resp.OverallSupport,
-		"Additional_Comments": resp.Note,
+	if strings.TrimSpace(cfg.WebhookURL) == "" {
+		// Write the searched locations to webhook.log so packaged EXEs report clearly
+		_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | webhook resolution failed; searched env ZOHO_WEBHOOK_URL, %s\n",
+			time.Now().UTC().Format(time.RFC3339), strings.Join(cfg.Searched, ", ")))
+		return nil, fmt.Errorf("no webhook URL configured")
 	}
+	if src, ok := cfg.Source("webhook_url"); ok {
+		_ = appendFile(getLogPath("webhook.log"), fmt.Sprintf("%s | webhook_url from %s\n", time.Now().UTC().Format(time.RFC3339), src))
+	}
+	return sink.NewWebhook("zoho-flow", cfg.WebhookURL, false, timeout, policy), nil
+}
 
-	// Log attempt
+// send delivers one response through the configured sink, skipping the
+// sinks in delivered that accepted it before, logging to webhook.log
+func send(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
 	webhookLogPath := getLogPath("webhook.log")
-	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | Using Zoho OAuth to %s\n",
-		time.Now().UTC().Format(time.RFC3339), zohoAuth.GetAPIEndpoint()))
 
-	// Submit to Zoho Creator
-	err := zohoAuth.SubmitToZohoCreator(data)
+	s, err := getSink()
 	if err != nil {
-		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | Zoho OAuth ERROR: %v\n",
-			time.Now().UTC().Format(time.RFC3339), err))
-		log.Printf("[zoho-oauth] Error: %v", err)
-		return err
+		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR resolving sinks: %v\n", time.Now().UTC().Format(time.RFC3339), err))
+		return delivered, err
 	}
+	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | sending %s via %s\n", time.Now().UTC().Format(time.RFC3339), resp.SubmissionID, s.Name()))
 
-	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | Zoho OAuth SUCCESS\n",
-		time.Now().UTC().Format(time.RFC3339)))
-	log.Printf("[zoho-oauth] Successfully submitted to Zoho Creator")
+	delivered, err = sink.Deliver(ctx, s, resp, delivered)
+	if err != nil {
+		_ = appendFile(webhookLogPath, fmt.Sprintf("%s | ERROR: %s (permanent=%v, accepted by %v): %v\n", time.Now().UTC().Format(time.RFC3339), s.Name(), retry.IsPermanent(err), delivered, err))
+		log.Printf("[%s] Giving up for now (permanent=%v): %v. Kept in local outbox", s.Name(), retry.IsPermanent(err), err)
+		return delivered, err
+	}
 
-	return nil
+	_ = appendFile(webhookLogPath, fmt.Sprintf("%s | SUCCESS: %s accepted %s\n", time.Now().UTC().Format(time.RFC3339), s.Name(), resp.SubmissionID))
+	return delivered, nil
 }
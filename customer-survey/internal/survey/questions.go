@@ -1,12 +0,0 @@
-package survey
-
-// Questions for the customer survey
-const (
-    Question1 = "How would you rate our service?"
-    Question2 = "How likely are you to recommend us to a friend?"
-    Question3 = "How satisfied are you with our product?"
-)
-
-// RatingScale defines the scale for the survey questions
-const RatingScaleMin = 1
-const RatingScaleMax = 10
\ No newline at end of file
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -9,49 +10,76 @@ import (
 	"net/http"
 	"os/exec"
 	"time"
+
+	"customer-survey/internal/survey"
+	"customer-survey/internal/ui/session"
 )
 
 //go:embed static
 var staticFiles embed.FS
 
-func RunDesktopUI() error {
+// RunDesktopUI serves the survey to the user's default browser until the
+// page is done with it (see package session) and reports how it ended
+func RunDesktopUI(ctx context.Context) (session.Outcome, error) {
 	// Start lightweight HTTP server
-	ln, port, err := getListener()
+	ln, port, err := getListener(survey.EphemeralPort())
 	if err != nil {
-		return err
+		return session.Interrupted, err
 	}
 	defer ln.Close()
 
+	sess := session.New(session.DefaultOptions)
+
 	// Setup routes
 	mux := http.NewServeMux()
 	sub, _ := fs.Sub(staticFiles, "static")
-	mux.Handle("/", http.FileServer(http.FS(sub)))
-	mux.HandleFunc("/submit", HandleSurveySubmission) // Match client-side script
+	files := http.FileServer(http.FS(sub))
+	page, _ := fs.ReadFile(sub, "index.html")
+	index := sess.Page(page) // carries the token /submit requires
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" || r.URL.Path == "/index.html" {
+			index.ServeHTTP(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+	mux.Handle("/submit", sess.Submissions(http.HandlerFunc(HandleSurveySubmission))) // Match client-side script
+	mux.HandleFunc("/definition", HandleDefinition)
+	mux.HandleFunc("/visible", HandleVisible)
+	sess.Handle(mux)
 
 	server := &http.Server{
-		Handler:      mux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		Handler:        mux,
+		ReadTimeout:    5 * time.Second,
+		WriteTimeout:   5 * time.Second,
+		MaxHeaderBytes: 64 << 10,
 	}
 
-	// Start server in background
-	go server.Serve(ln)
-
 	url := fmt.Sprintf("http://localhost:%d", port)
 
 	// Open ONLY in default browser (no Edge/Chrome spawning)
 	// This uses the user's already-running browser tab (minimal memory)
 	if err := exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start(); err != nil {
 		log.Printf("Failed to open browser: %v", err)
-		return err
+		return session.Interrupted, err
 	}
 
-	// Keep server alive for 5 minutes max
-	time.Sleep(5 * time.Minute)
-	return nil
+	// Serve until the user makes a choice or the page goes away
+	outcome, err := sess.Serve(ctx, server, ln)
+	log.Printf("Survey page ended: %s", outcome)
+	return outcome, err
 }
 
-func getListener() (net.Listener, int, error) {
+// getListener listens on the first free port of 8080-8090 on the loopback
+// address, or, with ephemeral, on a port the OS picks
+func getListener(ephemeral bool) (net.Listener, int, error) {
+	if ephemeral {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, 0, err
+		}
+		return ln, ln.Addr().(*net.TCPAddr).Port, nil
+	}
 	for p := 8080; p <= 8090; p++ {
 		ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", p))
 		if err == nil {
@@ -7,11 +7,38 @@ import (
 	"net/http"
 	"os"
 	userpkg "os/user"
+	"strings"
 
 	"customer-survey/internal/survey"
+	"customer-survey/pkg/definition"
 	"customer-survey/pkg/model"
+	"customer-survey/pkg/sysinfo"
 )
 
+// HandleDefinition returns the survey definition the form is rendered from
+func HandleDefinition(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(survey.Definition())
+}
+
+// HandleVisible returns the keys of the questions to show for the answers
+// posted so far, so display conditions are evaluated by the Go engine only
+func HandleVisible(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var incoming struct {
+		Answers map[string]interface{} `json:"answers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"visible": survey.Definition().Visible(incoming.Answers, sysinfo.Collect())})
+}
+
 // HandleSurveySubmission accepts JSON payload from the UI and forwards it to survey handler
 func HandleSurveySubmission(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -25,17 +52,20 @@ func HandleSurveySubmission(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var incoming struct {
-		SurveyResponse    string `json:"survey_response"`
-		ServerPerformance int    `json:"server_performance"`
-		TechnicalSupport  int    `json:"technical_support"`
-		OverallSupport    int    `json:"overall_support"`
-		Note              string `json:"note"`
-	}
+	// Only survey_response, the answers and the submission ID are taken from
+	// the page. Pages cached from older versions post top-level rating and
+	// note fields, which model.SurveyResponse moves into the answers.
+	var incoming model.SurveyResponse
 	if err := json.Unmarshal(body, &incoming); err != nil {
 		http.Error(w, "invalid json", http.StatusBadRequest)
 		return
 	}
+	// The page creates the ID when the user answers and sends it again when
+	// it retries, so a retry is the same outbox record; older pages send none
+	if incoming.SubmissionID != "" && !model.ValidSubmissionID(incoming.SubmissionID) {
+		http.Error(w, "invalid submission_id", http.StatusBadRequest)
+		return
+	}
 
 	// enrich with local data
 	hostname, _ := os.Hostname()
@@ -56,28 +86,48 @@ func HandleSurveySubmission(w http.ResponseWriter, r *http.Request) {
 	// Set survey_response to "completed" if not provided (for backward compatibility)
 	surveyResponse := incoming.SurveyResponse
 	if surveyResponse == "" {
-		surveyResponse = "completed"
+		surveyResponse = model.ResponseCompleted
 	}
 
-	resp := model.SurveyResponse{
-		ServerName:        hostname,
-		UserName:          user,
-		SurveyResponse:    surveyResponse,
-		ServerPerformance: incoming.ServerPerformance,
-		TechnicalSupport:  incoming.TechnicalSupport,
-		OverallSupport:    incoming.OverallSupport,
-		Note:              incoming.Note,
+	// A completed survey must answer the definition; the answers sent with
+	// a reminder or decline (a note) must fit it too, but none is required
+	def := survey.Definition()
+	var answers model.Answers
+	switch surveyResponse {
+	case model.ResponseCompleted:
+		answers, err = def.Check(incoming.Answers, sysinfo.Collect())
+	case model.ResponseDeclined, model.ResponseRemindLater:
+		answers, err = def.Answers(incoming.Answers, sysinfo.Collect())
+	default:
+		http.Error(w, "unknown survey_response", http.StatusBadRequest)
+		return
 	}
-
-	if err := survey.SubmitSurvey(r.Context(), resp); err != nil {
-		log.Printf("error submitting survey: %v (but backup may have been saved)", err)
-		// Still return success to user since data was backed up locally
+	if err != nil {
+		log.Printf("rejected survey submission: %v", err)
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"message":"submitted","note":"saved locally due to connection issue"}`))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid answers", "problems": err.(*definition.AnswerError).Problems})
 		return
 	}
 
+	resp := model.SurveyResponse{
+		SubmissionID:   strings.ToLower(incoming.SubmissionID),
+		ServerName:     hostname,
+		UserName:       user,
+		SurveyResponse: surveyResponse,
+		Answers:        answers,
+	}
+	def.Stamp(&resp)
+	resp.Campaign = survey.CampaignID(def, user)
+	// Answers from older pages get their ID here
+	resp.Stamp()
+
+	// Answer once the response is queued; the sinks and their retries can
+	// take longer than the page waits, and a page that times out submits again
+	if err := survey.QueueSurvey(resp); err != nil {
+		log.Printf("error queueing survey: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message":"submitted"}`))
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"customer-survey/internal/survey"
+	"customer-survey/pkg/model"
+)
+
+func TestSubmissionWithCustomDefinition(t *testing.T) {
+	appData := t.TempDir()
+	t.Setenv("APPDATA", appData)
+	dir := filepath.Join(appData, "CustomerSurvey")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	// A survey without a note question, delivered to a local file
+	definition := `{"id": "custom", "version": 1, "questions": [{"key": "speed", "type": "rating", "prompt": "Speed?", "required": true}]}`
+	if err := os.WriteFile(filepath.Join(dir, "survey.json"), []byte(definition), 0644); err != nil {
+		t.Fatalf("Failed to write definition: %v", err)
+	}
+	responses := filepath.Join(dir, "responses.jsonl")
+	cfg, _ := json.Marshal(map[string]interface{}{
+		"survey_definition": "survey.json",
+		"sinks":             []map[string]string{{"type": "file", "name": "local", "path": responses}},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), cfg, 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		// The page sends a note with every decline and reminder
+		{"declined", `{"survey_response": "declined", "answers": {"note": "User declined to participate in survey"}}`, http.StatusOK},
+		{"remind later", `{"survey_response": "remind_later", "answers": {"note": "User requested reminder later"}}`, http.StatusOK},
+		{"completed", `{"survey_response": "completed", "answers": {"speed": 3}}`, http.StatusOK},
+		{"misfitting answer", `{"survey_response": "declined", "answers": {"speed": 9}}`, http.StatusBadRequest},
+		{"unknown question", `{"survey_response": "completed", "answers": {"speed": 3, "note": "fast"}}`, http.StatusBadRequest},
+		{"unknown response", `{"survey_response": "partial"}`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		HandleSurveySubmission(rec, httptest.NewRequest(http.MethodPost, "/submit", strings.NewReader(tc.body)))
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := survey.WaitDeliveries(ctx); err != nil {
+		t.Fatalf("Failed to wait for deliveries: %v", err)
+	}
+	f, err := os.Open(responses)
+	if err != nil {
+		t.Fatalf("Failed to open delivered responses: %v", err)
+	}
+	defer f.Close()
+	got := map[string]model.SurveyResponse{}
+	for s := bufio.NewScanner(f); s.Scan(); {
+		var resp model.SurveyResponse
+		if err := json.Unmarshal(s.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to parse delivered response: %v", err)
+		}
+		got[resp.SurveyResponse] = resp
+	}
+	if len(got) != 3 {
+		t.Fatalf("Expected the three accepted responses to be delivered, got %v", got)
+	}
+	if _, ok := got[model.ResponseDeclined].Answers[model.KeyNote]; ok {
+		t.Errorf("The note should be dropped by a survey without a note question, got %v", got[model.ResponseDeclined].Answers)
+	}
+	if got[model.ResponseCompleted].SurveyID != "custom" {
+		t.Errorf("Responses should carry the custom survey, got %q", got[model.ResponseCompleted].SurveyID)
+	}
+}
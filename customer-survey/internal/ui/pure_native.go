@@ -2,14 +2,16 @@ package ui
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"syscall"
-	"time"
 	"unsafe"
 
 	"customer-survey/internal/survey"
+	"customer-survey/pkg/definition"
 	"customer-survey/pkg/model"
+	"customer-survey/pkg/sysinfo"
 )
 
 var (
@@ -39,15 +41,17 @@ const (
 	IDNO                 = 7
 )
 
-type SurveyData struct {
-	Performance int
-	Support     int
-	Overall     int
-	Feedback    string
-}
-
-// RunPureNativeGUI creates a pure Windows native GUI using only Windows API
+// RunPureNativeGUI creates a pure Windows native GUI using only Windows API.
+// A survey with questions message boxes cannot ask is shown in the browser
+// UI instead.
 func RunPureNativeGUI() error {
+	def := survey.Definition()
+	if err := nativeSupports(def); err != nil {
+		log.Printf("Showing the survey in the browser: %v", err)
+		_, err := RunDesktopUI(context.Background())
+		return err
+	}
+
 	// Step 1: Welcome prompt
 	title, _ := syscall.UTF16PtrFromString("ACE Customer Survey 🏢")
 	msg, _ := syscall.UTF16PtrFromString("Your Opinion Matters!\n\n" +
@@ -66,45 +70,74 @@ func RunPureNativeGUI() error {
 		return nil // User clicked No
 	}
 	
-	// Step 2: Collect survey responses
-	data := &SurveyData{}
-	
-	// Question 1: Server Performance
-	if !askRatingQuestion("Server Performance", "How would you rate our SERVER PERFORMANCE?", &data.Performance) {
-		return nil
+	// Step 2: Collect survey responses, one message box per question shown
+	system := sysinfo.Collect()
+	answers := model.Answers{}
+	for i, q := range def.Questions {
+		if !q.Shows(answers, system) {
+			continue
+		}
+		switch q.Type {
+		case definition.TypeRating:
+			var rating int
+			if !askRatingQuestion(fmt.Sprintf("Question %d of %d", i+1, len(def.Questions)), q.Prompt, &rating) {
+				return nil
+			}
+			answers[q.Key] = rating
+		case definition.TypeText:
+			if askFeedback(q.Prompt) {
+				answers[q.Key] = "User indicated they have feedback"
+			}
+		}
 	}
 	
-	// Question 2: Technical Support
-	if !askRatingQuestion("Technical Support", "How would you rate our TECHNICAL SUPPORT?", &data.Support) {
-		return nil
+	// Step 3: Check and submit
+	checked, err := def.Check(answers, system)
+	if err != nil {
+		log.Printf("Rejected survey answers: %v", err)
+		title, _ := syscall.UTF16PtrFromString("Survey Not Sent")
+		msg, _ := syscall.UTF16PtrFromString("Your answers could not be sent:\n\n" + err.Error())
+		procMessageBoxW.Call(
+			0,
+			uintptr(unsafe.Pointer(msg)),
+			uintptr(unsafe.Pointer(title)),
+			MB_OK|MB_ICONINFORMATION,
+		)
+		return err
 	}
+	submitSurveyData(def, checked)
 	
-	// Question 3: Overall Rating
-	if !askRatingQuestion("Overall Experience", "What is your OVERALL RATING?", &data.Overall) {
-		return nil
+	return nil
+}
+
+// nativeSupports reports a question message boxes cannot ask: Yes/No/Cancel
+// only expresses a Good/Okay/Bad rating or whether there is feedback
+func nativeSupports(def *definition.Definition) error {
+	for _, q := range def.Questions {
+		switch {
+		case q.Type == definition.TypeRating && q.Scale == 3, q.Type == definition.TypeText:
+		default:
+			return fmt.Errorf("%s question %q cannot be asked in message boxes", q.Type, q.Key)
+		}
 	}
-	
-	// Question 4: Feedback (optional)
+	return nil
+}
+
+// askFeedback asks about a text question. Message boxes cannot take text
+// input, so only whether the user has something to add is recorded.
+func askFeedback(prompt string) bool {
 	feedbackTitle, _ := syscall.UTF16PtrFromString("Additional Feedback")
-	feedbackMsg, _ := syscall.UTF16PtrFromString("Would you like to add any comments?\n\n" +
+	feedbackMsg, _ := syscall.UTF16PtrFromString(prompt + "\n\n" +
 		"(Note: Text input will be collected in next version.\n" +
 		"Click OK if you have feedback, Cancel to skip)")
 	
-	ret, _, _ = procMessageBoxW.Call(
+	ret, _, _ := procMessageBoxW.Call(
 		0,
 		uintptr(unsafe.Pointer(feedbackMsg)),
 		uintptr(unsafe.Pointer(feedbackTitle)),
 		MB_OKCANCEL|MB_ICONINFORMATION,
 	)
-	
-	if ret == IDOK {
-		data.Feedback = "User indicated they have feedback"
-	}
-	
-	// Step 3: Submit
-	submitSurveyData(data)
-	
-	return nil
+	return ret == IDOK
 }
 
 func askRatingQuestion(title, question string, rating *int) bool {
@@ -138,17 +171,12 @@ func askRatingQuestion(title, question string, rating *int) bool {
 	return true
 }
 
-func submitSurveyData(data *SurveyData) {
-	// Show submitting message
-	title, _ := syscall.UTF16PtrFromString("Submitting... ⏳")
-	msg, _ := syscall.UTF16PtrFromString("Submitting your feedback...\n\nPlease wait...")
-	procMessageBoxW.Call(
-		0,
-		uintptr(unsafe.Pointer(msg)),
-		uintptr(unsafe.Pointer(title)),
-		MB_OK|MB_ICONINFORMATION,
-	)
-	
+// submitSurveyData queues the answers and sends them in the background
+// while the user reads the thank-you box, as the browser UI does. The
+// sends follow survey_timeout and the retry policy; a response they do not
+// deliver stays in the outbox, and one a sink rejects for good is marked
+// failed there.
+func submitSurveyData(def *definition.Definition, answers model.Answers) {
 	// Get system info
 	username := os.Getenv("USERNAME")
 	if username == "" {
@@ -162,41 +190,38 @@ func submitSurveyData(data *SurveyData) {
 	
 	// Create response
 	resp := model.SurveyResponse{
-		ServerName:        servername,
-		UserName:          username,
-		SurveyResponse:    "Completed",
-		ServerPerformance: data.Performance,
-		TechnicalSupport:  data.Support,
-		OverallSupport:    data.Overall,
-		Note:              data.Feedback,
+		ServerName:     servername,
+		UserName:       username,
+		SurveyResponse: model.ResponseCompleted,
+		Answers:        answers,
 	}
-	
-	// Submit to webhook
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	
-	if err := survey.SubmitSurvey(ctx, resp); err != nil {
-		log.Printf("Submission error: %v (saved locally)", err)
-		title, _ = syscall.UTF16PtrFromString("✓ Saved Offline")
-		msg, _ = syscall.UTF16PtrFromString("✓ Feedback saved locally!\n\n" +
-			"Your response has been saved.\n" +
-			"We'll sync it when you're back online.")
-		procMessageBoxW.Call(
-			0,
-			uintptr(unsafe.Pointer(msg)),
-			uintptr(unsafe.Pointer(title)),
-			MB_OK|MB_ICONINFORMATION,
-		)
+	def.Stamp(&resp)
+	resp.Campaign = survey.CampaignID(def, username)
+	resp.Stamp()
+	
+	// Queue and send in the background
+	var title, msg *uint16
+	if err := survey.QueueSurvey(resp); err != nil {
+		log.Printf("Submission error: %v", err)
+		title, _ = syscall.UTF16PtrFromString("Not Saved")
+		msg, _ = syscall.UTF16PtrFromString("Your feedback could not be saved on this computer.\n\n" +
+			"It is being sent now, but will be lost if that fails.")
 	} else {
 		title, _ = syscall.UTF16PtrFromString("✓ Thank You!")
 		msg, _ = syscall.UTF16PtrFromString("✓ Thank you for your feedback!\n\n" +
-			"Your response has been submitted successfully.\n\n" +
+			"Your response has been recorded.\n\n" +
 			"We appreciate your time and valuable input!")
-		procMessageBoxW.Call(
-			0,
-			uintptr(unsafe.Pointer(msg)),
-			uintptr(unsafe.Pointer(title)),
-			MB_OK|MB_ICONINFORMATION,
-		)
+	}
+	procMessageBoxW.Call(
+		0,
+		uintptr(unsafe.Pointer(msg)),
+		uintptr(unsafe.Pointer(title)),
+		MB_OK|MB_ICONINFORMATION,
+	)
+
+	// Every send is bounded by QueueSurvey; what is not delivered by then
+	// is replayed on the next launch
+	if err := survey.WaitDeliveries(context.Background()); err != nil {
+		log.Printf("Waiting for the survey to be sent: %v", err)
 	}
 }
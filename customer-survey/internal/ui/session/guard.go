@@ -0,0 +1,74 @@
+package session
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// TokenHeader carries the session's token on submissions
+const TokenHeader = "X-Survey-Token"
+
+// TokenPlaceholder in the served page is replaced by the session's token
+const TokenPlaceholder = "{{SURVEY_TOKEN}}"
+
+// newToken returns a random secret for one launch
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on the supported platforms
+		panic("session: no random token: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Token returns the secret the page must send with submissions
+func (s *Session) Token() string {
+	return s.token
+}
+
+// Page serves page with TokenPlaceholder replaced by the token. The page
+// is not cached, so a reload after a restart picks up the new token.
+func (s *Session) Page(page []byte) http.Handler {
+	body := bytes.ReplaceAll(page, []byte(TokenPlaceholder), []byte(s.token))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(body)
+	})
+}
+
+// validToken reports whether r carries the session's token
+func (s *Session) validToken(r *http.Request) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(TokenHeader)), []byte(s.token)) == 1
+}
+
+// guard lets through only requests addressed to the server on port by the
+// page itself: the Host must name the loopback address, so pages on other
+// sites cannot reach it by pointing their own names at 127.0.0.1; an
+// Origin, which browsers send with cross-site requests, must be the
+// server's. Request bodies are limited to MaxBodyBytes.
+func (s *Session) guard(next http.Handler, port int) http.Handler {
+	allowed := map[string]bool{}
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		allowed[net.JoinHostPort(host, strconv.Itoa(port))] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed[r.Host] {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+			http.Error(w, "forbidden origin", http.StatusForbidden)
+			return
+		}
+		if s.opts.MaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
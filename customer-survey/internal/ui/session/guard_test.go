@@ -0,0 +1,85 @@
+package session
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGuard(t *testing.T) {
+	s := New(testOptions)
+	mux := http.NewServeMux()
+	s.Handle(mux)
+	mux.Handle("/", s.Page([]byte(`<meta name="survey-token" content="`+TokenPlaceholder+`">`)))
+	mux.Handle("/submit", s.Submissions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":"submitted"}`))
+	})))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	host := ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Serve(ctx, &http.Server{Handler: mux}, ln)
+
+	page, err := http.Get("http://" + host + "/")
+	if err != nil {
+		t.Fatalf("Failed to load the page: %v", err)
+	}
+	body, _ := io.ReadAll(page.Body)
+	page.Body.Close()
+	if !strings.Contains(string(body), s.Token()) || len(s.Token()) < 32 {
+		t.Fatalf("The page should carry the token, got %s", body)
+	}
+	if page.Header.Get("Cache-Control") != "no-store" {
+		t.Error("The page should not be cached")
+	}
+	if New(testOptions).Token() == s.Token() {
+		t.Error("Every session should have its own token")
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		header map[string]string
+		body   string
+		want   int
+	}{
+		{"no token", "", nil, `{"survey_response":"partial"}`, http.StatusForbidden},
+		{"wrong token", "", map[string]string{TokenHeader: "guess"}, `{"survey_response":"partial"}`, http.StatusForbidden},
+		{"foreign origin", "", map[string]string{TokenHeader: s.Token(), "Origin": "https://evil.example"}, `{"survey_response":"partial"}`, http.StatusForbidden},
+		{"rebound host", "evil.example:80", map[string]string{TokenHeader: s.Token()}, `{"survey_response":"partial"}`, http.StatusForbidden},
+		{"too large", "", map[string]string{TokenHeader: s.Token()}, `{"survey_response":"partial","note":"` + strings.Repeat("x", 2<<10) + `"}`, http.StatusRequestEntityTooLarge},
+		{"own page", "", map[string]string{TokenHeader: s.Token(), "Origin": "http://" + host}, `{"survey_response":"partial"}`, http.StatusOK},
+		{"localhost", "localhost:" + host[strings.LastIndex(host, ":")+1:], map[string]string{TokenHeader: s.Token()}, `{"survey_response":"partial"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+host+"/submit", strings.NewReader(tt.body))
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, res.StatusCode)
+		}
+	}
+
+	// Heartbeats need no token, but come from the page's origin only
+	req, _ := http.NewRequest(http.MethodPost, "http://"+host+"/heartbeat", nil)
+	req.Header.Set("Origin", "null")
+	if res, err := http.DefaultClient.Do(req); err != nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("A heartbeat from another origin should be refused: %v %v", res, err)
+	}
+}
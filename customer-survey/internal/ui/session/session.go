@@ -0,0 +1,265 @@
+// Package session keeps the local survey server of the browser UI up for
+// as long as the survey page needs it: until the user makes a choice,
+// closes the page or stops sending heartbeats. The server then shuts down
+// gracefully, letting submissions in flight finish.
+//
+// The server only answers the page it served: requests must be addressed
+// to the loopback address and come from its origin, and submissions must
+// carry a secret that is new on every launch and only in the served page.
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"customer-survey/pkg/model"
+)
+
+// Outcome is how a session ended
+type Outcome string
+
+const (
+	Submitted   Outcome = model.ResponseCompleted   // the survey was submitted
+	Declined    Outcome = model.ResponseDeclined    // No Thanks
+	Reminded    Outcome = model.ResponseRemindLater // Remind Me Later
+	Closed      Outcome = "closed"                  // the page was closed without a choice
+	Idle        Outcome = "idle"                    // the page stopped sending heartbeats, or never opened
+	Expired     Outcome = "expired"                 // MaxLifetime was reached
+	Interrupted Outcome = "interrupted"             // the caller's context was cancelled
+)
+
+// ExitCode is the process exit status for an outcome: 0 when the user made
+// a choice, 2 when the page was closed without one, 3 when it went idle or
+// expired, 4 when interrupted
+func (o Outcome) ExitCode() int {
+	switch o {
+	case Submitted, Declined, Reminded:
+		return 0
+	case Closed:
+		return 2
+	case Idle, Expired:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Final reports whether a survey_response value ends the session. An empty
+// one is a completed survey, as in the submission handler.
+func Final(surveyResponse string) (Outcome, bool) {
+	if surveyResponse == "" {
+		return Submitted, true
+	}
+	switch o := Outcome(surveyResponse); o {
+	case Submitted, Declined, Reminded:
+		return o, true
+	}
+	return "", false
+}
+
+// Options are the session's timeouts
+type Options struct {
+	OpenTimeout  time.Duration // for the page's first heartbeat, while the browser starts
+	IdleTimeout  time.Duration // between heartbeats; background tabs may send one a minute only
+	CloseGrace   time.Duration // after the page reports it closed, in case it was only reloaded
+	MaxLifetime  time.Duration // of the whole session
+	DrainTimeout time.Duration // for requests in flight at shutdown, at least the server's read and write timeouts
+	MaxBodyBytes int64         // of a request, 0 for no limit
+}
+
+// DefaultOptions are the timeouts of the desktop UI. The drain covers a
+// submission that is being queued: /submit answers once the response is in
+// the outbox and the sinks are sent to in the background (see
+// survey.QueueSurvey), which outlives the server.
+var DefaultOptions = Options{
+	OpenTimeout:  2 * time.Minute,
+	IdleTimeout:  3 * time.Minute,
+	CloseGrace:   5 * time.Second,
+	MaxLifetime:  2 * time.Hour,
+	DrainTimeout: 15 * time.Second,
+	MaxBodyBytes: 1 << 20,
+}
+
+// Session tracks the survey page
+type Session struct {
+	opts    Options
+	mu      sync.Mutex
+	timer   *time.Timer // fires when the page is considered gone
+	closing bool        // the page reported it closed; the timer runs CloseGrace
+	done    chan struct{}
+	outcome Outcome
+	token   string // the page's secret, see Page
+}
+
+// New starts a session; the page has OpenTimeout to send its first heartbeat
+func New(opts Options) *Session {
+	s := &Session{opts: opts, done: make(chan struct{}), token: newToken()}
+	s.timer = time.AfterFunc(opts.OpenTimeout, s.gone)
+	return s
+}
+
+// Heartbeat records that the page is open
+func (s *Session) Heartbeat() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing = false
+	s.timer.Reset(s.opts.IdleTimeout)
+}
+
+// PageClosed records that the page was closed or reloaded. Unless a
+// heartbeat follows within CloseGrace, the session ends as Closed.
+func (s *Session) PageClosed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing = true
+	s.timer.Reset(s.opts.CloseGrace)
+}
+
+// End ends the session with an outcome; later calls are ignored
+func (s *Session) End(o Outcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome != "" {
+		return
+	}
+	s.outcome = o
+	s.timer.Stop()
+	close(s.done)
+}
+
+func (s *Session) gone() {
+	s.mu.Lock()
+	closing := s.closing
+	s.mu.Unlock()
+	if closing {
+		s.End(Closed)
+	} else {
+		s.End(Idle)
+	}
+}
+
+// Wait blocks until the session ends, MaxLifetime passes or ctx is done
+func (s *Session) Wait(ctx context.Context) Outcome {
+	lifetime := time.NewTimer(s.opts.MaxLifetime)
+	defer lifetime.Stop()
+	select {
+	case <-s.done:
+	case <-lifetime.C:
+		s.End(Expired)
+	case <-ctx.Done():
+		s.End(Interrupted)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outcome
+}
+
+// Handle registers the page's endpoints: POST /heartbeat while it is open,
+// POST /closed (a beacon) when it is closed
+func (s *Session) Handle(mux *http.ServeMux) {
+	mux.HandleFunc("/heartbeat", s.handle(s.Heartbeat))
+	mux.HandleFunc("/closed", s.handle(s.PageClosed))
+}
+
+func (s *Session) handle(record func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		record()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Submissions wraps the submission handler: it refuses requests without
+// the token, and once it accepts a final survey_response, the session
+// ends with it
+func (s *Session) Submissions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.validToken(r) {
+			http.Error(w, "invalid token", http.StatusForbidden)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "could not read body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var peek struct {
+			SurveyResponse string `json:"survey_response"`
+		}
+		json.Unmarshal(body, &peek)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if o, ok := Final(peek.SurveyResponse); ok && r.Method == http.MethodPost && rec.status < 300 {
+			s.End(o)
+		}
+	})
+}
+
+// statusRecorder remembers the status a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Serve runs server on ln until the session ends, then shuts it down,
+// waiting up to DrainTimeout for requests in flight. Requests are checked
+// as described in the package documentation.
+func (s *Session) Serve(ctx context.Context, server *http.Server, ln net.Listener) (Outcome, error) {
+	handler := server.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	server.Handler = s.guard(handler, ln.Addr().(*net.TCPAddr).Port)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+
+	var outcome Outcome
+	select {
+	case err := <-served:
+		// The server failed before the session ended
+		s.End(Interrupted)
+		return Interrupted, err
+	case outcome = <-s.waitAsync(ctx):
+	}
+
+	// A request the server still allows to finish is not cut off
+	timeout := s.opts.DrainTimeout
+	if limit := server.ReadTimeout + server.WriteTimeout; limit > timeout {
+		timeout = limit
+	}
+	drain, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(drain)
+	if serveErr := <-served; err == nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		err = serveErr
+	}
+	return outcome, err
+}
+
+func (s *Session) waitAsync(ctx context.Context) <-chan Outcome {
+	out := make(chan Outcome, 1)
+	go func() { out <- s.Wait(ctx) }()
+	return out
+}
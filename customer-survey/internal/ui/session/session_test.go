@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testOptions = Options{
+	OpenTimeout:  200 * time.Millisecond,
+	IdleTimeout:  200 * time.Millisecond,
+	CloseGrace:   50 * time.Millisecond,
+	MaxLifetime:  5 * time.Second,
+	DrainTimeout: 2 * time.Second,
+	MaxBodyBytes: 1 << 10,
+}
+
+// ended reports whether the session ended within d
+func ended(s *Session, d time.Duration) bool {
+	select {
+	case <-s.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	s := New(testOptions)
+	for i := 0; i < 8; i++ {
+		time.Sleep(50 * time.Millisecond)
+		s.Heartbeat()
+	}
+	if ended(s, 0) {
+		t.Fatal("A page sending heartbeats should keep the session open")
+	}
+	if o := s.Wait(context.Background()); o != Idle {
+		t.Errorf("Expected %s once heartbeats stop, got %s", Idle, o)
+	}
+	if Idle.ExitCode() == 0 || Submitted.ExitCode() != 0 {
+		t.Error("Only a choice of the user should exit with 0")
+	}
+}
+
+func TestPageClosed(t *testing.T) {
+	s := New(testOptions)
+	s.Heartbeat()
+
+	// A reload closes the page and opens it again
+	s.PageClosed()
+	s.Heartbeat()
+	if ended(s, 100*time.Millisecond) {
+		t.Fatal("A reloaded page should keep the session open")
+	}
+
+	s.PageClosed()
+	if o := s.Wait(context.Background()); o != Closed {
+		t.Errorf("Expected %s, got %s", Closed, o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if o := New(testOptions).Wait(ctx); o != Interrupted {
+		t.Errorf("Expected %s, got %s", Interrupted, o)
+	}
+}
+
+func TestServeDrainsSubmission(t *testing.T) {
+	opts := testOptions
+	opts.IdleTimeout = 5 * time.Second
+	opts.DrainTimeout = 10 * time.Millisecond // the server's write timeout wins
+	s := New(opts)
+	mux := http.NewServeMux()
+	s.Handle(mux)
+	mux.Handle("/submit", s.Submissions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "bad") {
+			http.Error(w, "invalid answers", http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(string(body), "slow") {
+			time.Sleep(300 * time.Millisecond) // delivering the response
+		}
+		w.Write([]byte(`{"message":"submitted"}`))
+	})))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	url := "http://" + ln.Addr().String()
+	type result struct {
+		outcome Outcome
+		err     error
+	}
+	served := make(chan result, 1)
+	go func() {
+		o, err := s.Serve(context.Background(), &http.Server{Handler: mux, WriteTimeout: 2 * time.Second}, ln)
+		served <- result{o, err}
+	}()
+
+	post := func(path, body string) (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodPost, url+path, strings.NewReader(body))
+		req.Header.Set(TokenHeader, s.Token())
+		return http.DefaultClient.Do(req)
+	}
+	if res, err := post("/heartbeat", ""); err != nil || res.StatusCode != http.StatusNoContent {
+		t.Fatalf("Heartbeat failed: %v %v", res, err)
+	}
+	// Rejected answers and responses that are not a choice keep it open
+	post("/submit", `{"survey_response":"completed","answers":{"bad":1}}`)
+	post("/submit", `{"survey_response":"partial"}`)
+	if ended(s, 0) {
+		t.Fatal("Only an accepted choice should end the session")
+	}
+
+	// A submission still in flight when the session ends is answered
+	inFlight := make(chan error, 1)
+	go func() {
+		res, err := post("/submit", `{"survey_response":"partial","answers":{"slow":1}}`)
+		if err == nil && res.StatusCode != http.StatusOK {
+			err = fmt.Errorf("status %d", res.StatusCode)
+		}
+		inFlight <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	res, err := post("/submit", `{"survey_response":"declined"}`)
+	if err != nil {
+		t.Fatalf("The submission in flight should be answered: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), "submitted") {
+		t.Errorf("Unexpected answer: %d %s", res.StatusCode, body)
+	}
+
+	select {
+	case r := <-served:
+		if r.outcome != Declined || r.err != nil {
+			t.Errorf("Expected %s without error, got %s, %v", Declined, r.outcome, r.err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("The server should shut down after the choice")
+	}
+	if err := <-inFlight; err != nil {
+		t.Errorf("The submission in flight should be drained: %v", err)
+	}
+	if _, err := post("/heartbeat", ""); err == nil {
+		t.Error("The server should no longer accept requests")
+	}
+}
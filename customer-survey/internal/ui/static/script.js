@@ -1,3 +1,29 @@
+// The app serves this page with a secret for this launch; /submit refuses
+// requests without it, so other pages cannot post answers in the user's name
+const SURVEY_TOKEN = document.querySelector('meta[name="survey-token"]').content;
+
+// Every answer gets one submission id, sent again when it is retried after
+// an error, so the app queues and delivers it only once
+const submissionIds = {};
+
+function submissionId(surveyResponse) {
+  if (!submissionIds[surveyResponse]) {
+    submissionIds[surveyResponse] = newSubmissionId();
+  }
+  return submissionIds[surveyResponse];
+}
+
+function newSubmissionId() {
+  if (crypto.randomUUID) {
+    return crypto.randomUUID();
+  }
+  const b = crypto.getRandomValues(new Uint8Array(16));
+  b[6] = (b[6] & 0x0f) | 0x40; // version 4
+  b[8] = (b[8] & 0x3f) | 0x80; // RFC 4122 variant
+  const h = Array.from(b, x => x.toString(16).padStart(2, '0')).join('');
+  return `${h.slice(0, 8)}-${h.slice(8, 12)}-${h.slice(12, 16)}-${h.slice(16, 20)}-${h.slice(20)}`;
+}
+
 // Handle Yes button - show survey form
 function handleYes() {
   document.getElementById('promptScreen').classList.add('hidden');
@@ -16,18 +42,18 @@ async function handleRemindLater() {
   statusEl.className = 'status success show';
   
   // Submit reminder response
+  stopHeartbeat();
   try {
     await fetch('/submit', {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-Survey-Token': SURVEY_TOKEN,
       },
       body: JSON.stringify({
         survey_response: 'remind_later',
-        server_performance: 0,
-        technical_support: 0,
-        overall_support: 0,
-        note: 'User requested reminder later'
+        submission_id: submissionId('remind_later'),
+        answers: { note: 'User requested reminder later' }
       })
     });
   } catch (error) {
@@ -57,17 +83,17 @@ async function handleNo() {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-Survey-Token': SURVEY_TOKEN,
       },
       body: JSON.stringify({
         survey_response: 'declined',
-        server_performance: 0,
-        technical_support: 0,
-        overall_support: 0,
-        note: 'User declined to participate in survey'
+        submission_id: submissionId('declined'),
+        answers: { note: 'User declined to participate in survey' }
       })
     });
     
     if (response.ok) {
+      stopHeartbeat();
       statusEl.textContent = 'Thank you! Your preference has been recorded.';
       statusEl.className = 'status success show';
       
@@ -89,20 +115,36 @@ async function handleNo() {
 }
 
 let submitting = false;
+let surveyDefinition = null;
 
-// Submit form with 1-2-3 rating system
+// Answers the server rejected, one problem per question
+class AnswerError extends Error {
+  constructor(problems) {
+    super('invalid answers');
+    this.problems = problems;
+  }
+}
+
+// Choices of a rating question, best first
+const RATING_OPTIONS = [
+  { value: 3, emoji: '😊', label: 'Good' },
+  { value: 2, emoji: '😐', label: 'Okay' },
+  { value: 1, emoji: '☹️', label: 'Bad' },
+];
+
+// Points of a Likert question, from 1 to 5
+const LIKERT_LABELS = ['Strongly disagree', 'Disagree', 'Neutral', 'Agree', 'Strongly agree'];
+
+// Submit the answers keyed by question key
 async function submitForm() {
-  if (submitting) return;
+  if (submitting || !surveyDefinition) return;
   
-  // Get selected ratings (1, 2, or 3)
-  const q1Radio = document.querySelector('input[name="q1"]:checked');
-  const q2Radio = document.querySelector('input[name="q2"]:checked');
-  const q3Radio = document.querySelector('input[name="q3"]:checked');
+  const { answers, missing } = collectAnswers(surveyDefinition);
   
-  // Validate all questions are answered
-  if (!q1Radio || !q2Radio || !q3Radio) {
+  // Validate all required questions are answered
+  if (missing.length > 0) {
     const status = document.getElementById('formStatus');
-    status.textContent = 'Please answer all three questions before submitting.';
+    status.textContent = 'Please answer all required questions before submitting.';
     status.className = 'status error show';
     
     setTimeout(() => {
@@ -121,27 +163,22 @@ async function submitForm() {
   submitBtn.textContent = 'Submitting your feedback...';
   submitBtn.style.opacity = '0.7';
   
-  const q1 = parseInt(q1Radio ? q1Radio.value : "0", 10);
-  const q2 = parseInt(q2Radio ? q2Radio.value : "0", 10);
-  const q3 = parseInt(q3Radio ? q3Radio.value : "0", 10);
-  const note = document.getElementById('note').value.trim() || "";
-  
   try {
     const response = await fetch('/submit', {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
+        'X-Survey-Token': SURVEY_TOKEN,
       },
       body: JSON.stringify({
         survey_response: 'completed',
-        server_performance: q1,
-        technical_support: q2,
-        overall_support: q3,
-        note: note
+        submission_id: submissionId('completed'),
+        answers: answers
       })
     });
     
     if (response.ok) {
+      stopHeartbeat();
       // Hide survey form and show thank you screen
       document.getElementById('surveyFormContainer').classList.add('hidden');
       document.getElementById('thankYouScreen').classList.remove('hidden');
@@ -151,6 +188,10 @@ async function submitForm() {
         window.close();
       }, 3500);
       
+    } else if (response.status === 400) {
+      // The server checked the answers against the survey definition
+      const result = await response.json().catch(() => ({}));
+      throw new AnswerError(result.problems || []);
     } else {
       const errorText = await response.text();
       throw new Error(errorText);
@@ -158,7 +199,9 @@ async function submitForm() {
     
   } catch (error) {
     console.error('Submission error:', error);
-    status.textContent = 'Something went wrong. Please try again.';
+    status.textContent = error instanceof AnswerError
+      ? 'Please check your answers: ' + error.problems.join('; ')
+      : 'Something went wrong. Please try again.';
     status.className = 'status error show';
     
     // Reset button
@@ -169,14 +212,257 @@ async function submitForm() {
   }
 }
 
-// Add smooth interactions on page load
-document.addEventListener('DOMContentLoaded', function() {
-  // Auto-resize textarea
-  const textarea = document.getElementById('note');
-  if (textarea) {
-    textarea.addEventListener('input', function() {
-      this.style.height = 'auto';
-      this.style.height = Math.max(35, this.scrollHeight) + 'px';
+// Read the answer to every shown question; missing lists unanswered required keys
+function collectAnswers(definition) {
+  const answers = {};
+  const missing = [];
+  definition.questions.forEach(q => {
+    if (!isShown(q)) return;
+    const value = readAnswer(q);
+    if (value !== null) answers[q.key] = value;
+    if (q.required && value === null) missing.push(q.key);
+  });
+  return { answers, missing };
+}
+
+// Answer of one question in the form the server expects, or null
+function readAnswer(q) {
+  switch (q.type) {
+    case 'text': {
+      const input = document.getElementById('answer_' + q.key);
+      const value = input ? input.value.trim() : '';
+      return value || null;
+    }
+    case 'multi_choice': {
+      const picked = Array.from(document.querySelectorAll(`input[name="${q.key}"]:checked`)).map(el => el.value);
+      return picked.length > 0 ? picked : null;
+    }
+    case 'choice': {
+      const checked = document.querySelector(`input[name="${q.key}"]:checked`);
+      return checked ? checked.value : null;
+    }
+    default: {
+      const checked = document.querySelector(`input[name="${q.key}"]:checked`);
+      return checked ? parseInt(checked.value, 10) : null;
+    }
+  }
+}
+
+// Every answer given so far, including those of hidden questions
+function currentAnswers(definition) {
+  const answers = {};
+  definition.questions.forEach(q => {
+    const value = readAnswer(q);
+    if (value !== null) answers[q.key] = value;
+  });
+  return answers;
+}
+
+function isShown(q) {
+  const el = document.querySelector(`#questions [data-key="${q.key}"]`);
+  return !!el && !el.classList.contains('hidden');
+}
+
+// Show only the questions the survey engine lists
+function applyVisibility(visible) {
+  document.querySelectorAll('#questions [data-key]').forEach(el => {
+    el.classList.toggle('hidden', !visible.includes(el.dataset.key));
+  });
+}
+
+// Build the form from the survey definition. Which questions are shown is
+// decided by the Go survey engine and updated whenever an answer changes.
+function renderQuestions(definition) {
+  const container = document.getElementById('questions');
+  container.innerHTML = '';
+  definition.questions.forEach(q => {
+    const el = renderQuestion(q);
+    el.dataset.key = q.key;
+    if (q.show_if) el.classList.add('hidden');
+    container.appendChild(el);
+  });
+  container.addEventListener('change', updateVisibility);
+  updateVisibility();
+}
+
+function renderQuestion(q) {
+  if (q.type === 'text') {
+    const wrapper = document.createElement('div');
+    wrapper.className = 'textarea-container';
+    const textarea = document.createElement('textarea');
+    textarea.id = 'answer_' + q.key;
+    textarea.placeholder = q.prompt;
+    textarea.maxLength = q.max_length || 200;
+    textarea.addEventListener('input', autoResize);
+    wrapper.appendChild(textarea);
+    return wrapper;
+  }
+  
+  const group = document.createElement('div');
+  group.className = 'question-group';
+  const label = document.createElement('div');
+  label.className = 'question-label';
+  const text = document.createElement('span');
+  text.textContent = q.prompt;
+  label.appendChild(text);
+  group.appendChild(label);
+  
+  switch (q.type) {
+    case 'nps':
+      group.appendChild(renderScale(q, range(0, 10).map(n => ({ value: n, label: String(n) }))));
+      group.appendChild(renderRangeLabels('Not likely', 'Very likely'));
+      break;
+    case 'likert':
+      group.appendChild(renderScale(q, LIKERT_LABELS.map((l, i) => ({ value: i + 1, label: l }))));
+      break;
+    case 'stars':
+      group.appendChild(renderScale(q, range(1, q.scale || 5).map(n => ({ value: n, emoji: '★', label: String(n) }))));
+      break;
+    case 'choice':
+    case 'multi_choice':
+      group.appendChild(renderChoices(q));
+      break;
+    default:
+      group.appendChild(renderScale(q, RATING_OPTIONS));
+  }
+  return group;
+}
+
+// One row of radio buttons styled like the Good/Okay/Bad options
+function renderScale(q, choices) {
+  const options = document.createElement('div');
+  options.className = choices.length > 3 ? 'rating-options scale-options' : 'rating-options';
+  choices.forEach(opt => {
+    const id = `${q.key}_${opt.value}`;
+    const option = document.createElement('div');
+    option.className = 'rating-option';
+    const input = document.createElement('input');
+    input.type = 'radio';
+    input.id = id;
+    input.name = q.key;
+    input.value = opt.value;
+    input.required = !!q.required;
+    const optionLabel = document.createElement('label');
+    optionLabel.htmlFor = id;
+    if (opt.emoji) {
+      const emoji = document.createElement('span');
+      emoji.className = 'rating-emoji';
+      emoji.textContent = opt.emoji;
+      optionLabel.appendChild(emoji);
+    }
+    const name = document.createElement('span');
+    name.className = 'rating-label';
+    name.textContent = opt.label;
+    optionLabel.appendChild(name);
+    option.appendChild(input);
+    option.appendChild(optionLabel);
+    options.appendChild(option);
+  });
+  return options;
+}
+
+// Radio buttons (choice) or checkboxes (multi_choice), one option per line
+function renderChoices(q) {
+  const options = document.createElement('div');
+  options.className = 'choice-options';
+  (q.options || []).forEach((value, i) => {
+    const label = document.createElement('label');
+    label.className = 'choice-option';
+    const input = document.createElement('input');
+    input.type = q.type === 'multi_choice' ? 'checkbox' : 'radio';
+    input.name = q.key;
+    input.id = `${q.key}_${i}`;
+    input.value = value;
+    const text = document.createElement('span');
+    text.textContent = value;
+    label.appendChild(input);
+    label.appendChild(text);
+    options.appendChild(label);
+  });
+  return options;
+}
+
+function renderRangeLabels(low, high) {
+  const labels = document.createElement('div');
+  labels.className = 'range-labels';
+  [low, high].forEach(t => {
+    const span = document.createElement('span');
+    span.textContent = t;
+    labels.appendChild(span);
+  });
+  return labels;
+}
+
+function range(from, to) {
+  const out = [];
+  for (let n = from; n <= to; n++) out.push(n);
+  return out;
+}
+
+// Ask the Go survey engine which questions are shown for the answers so far
+async function updateVisibility() {
+  if (!surveyDefinition) return;
+  try {
+    const response = await fetch('/visible', {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({ answers: currentAnswers(surveyDefinition) })
     });
+    if (!response.ok) throw new Error(await response.text());
+    const result = await response.json();
+    applyVisibility(result.visible || []);
+  } catch (error) {
+    console.error('Could not evaluate question conditions:', error);
   }
+}
+
+// Auto-resize textarea
+function autoResize() {
+  this.style.height = 'auto';
+  this.style.height = Math.max(35, this.scrollHeight) + 'px';
+}
+
+// Load the survey definition served by the app
+async function loadDefinition() {
+  try {
+    const response = await fetch('/definition');
+    if (!response.ok) throw new Error(await response.text());
+    surveyDefinition = await response.json();
+    renderQuestions(surveyDefinition);
+  } catch (error) {
+    console.error('Could not load survey definition:', error);
+    const status = document.getElementById('formStatus');
+    status.textContent = 'The survey could not be loaded.';
+    status.className = 'status error show';
+  }
+}
+
+// The app serves this page only while it sends heartbeats; once the user
+// made a choice it shuts down, so they stop
+const HEARTBEAT_MS = 20000;
+let heartbeat = null;
+
+function sendHeartbeat() {
+  fetch('/heartbeat', { method: 'POST' }).catch(() => {});
+}
+
+function startHeartbeat() {
+  sendHeartbeat();
+  heartbeat = setInterval(sendHeartbeat, HEARTBEAT_MS);
+}
+
+function stopHeartbeat() {
+  clearInterval(heartbeat);
+}
+
+// Closing the page ends the app's session (a reload starts a new one in time)
+window.addEventListener('pagehide', () => {
+  navigator.sendBeacon('/closed');
+});
+
+document.addEventListener('DOMContentLoaded', function() {
+  loadDefinition();
+  startHeartbeat();
 });
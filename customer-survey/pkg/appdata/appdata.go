@@ -0,0 +1,69 @@
+// Package appdata locates the per-user data folder of the survey and
+// provides the file primitives every store in it uses: advisory locks
+// between processes and atomic replacement of files.
+package appdata
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the per-user AppData folder for the survey app
+func Dir() string {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		appData = os.Getenv("USERPROFILE")
+	}
+	return filepath.Join(appData, "CustomerSurvey")
+}
+
+// WriteFileAtomic replaces path with data. It is written to a temporary
+// file next to path and renamed over it, so readers see the old content or
+// the new one, never a part of it, even if the process dies halfway.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LockFile takes an exclusive advisory lock on path, creating it, and
+// waits while another process or goroutine holds it. The lock is released
+// by calling unlock, or by the system if the process dies.
+func LockFile(path string) (unlock func() error, err error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		err := unlockFile(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package appdata
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes a flock on f, waiting for it
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
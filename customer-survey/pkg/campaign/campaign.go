@@ -0,0 +1,213 @@
+// Package campaign describes survey campaigns: a run of the survey with
+// its own id, active window, target audience and question set version.
+// Done, no-thanks and remind-later state is kept per campaign (see
+// pkg/startup), so a new campaign asks everybody again while an opt-out
+// only covers the campaign it was given for.
+package campaign
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"customer-survey/pkg/sysinfo"
+)
+
+// DateLayout is the day format of "start" and "end"; RFC 3339 times are
+// accepted as well
+const DateLayout = "2006-01-02"
+
+// idPattern keeps ids usable as folder names
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Campaign is one entry of the "campaigns" list
+type Campaign struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name,omitempty"`
+	Start         string   `json:"start,omitempty"`          // first day, empty for no start
+	End           string   `json:"end,omitempty"`            // last day (inclusive), empty for no end
+	Audience      Audience `json:"audience,omitempty"`       // empty for everybody
+	SurveyVersion int      `json:"survey_version,omitempty"` // question set version, 0 for any
+	SamplePercent float64  `json:"sample_percent,omitempty"` // share of the audience asked, 0 for everybody
+}
+
+// Audience selects who a campaign is asked of. Each list is optional; a
+// user is targeted when they match every list that is set. Users and
+// computers are matched case-insensitively and may use * and ? wildcards.
+type Audience struct {
+	Users        []string `json:"users,omitempty"`
+	Computers    []string `json:"computers,omitempty"`
+	MachineTypes []string `json:"machine_types,omitempty"` // "server" or "workstation"
+}
+
+// Target is the user and machine a campaign is evaluated for
+type Target struct {
+	User     string
+	Computer string
+	System   sysinfo.Attributes
+}
+
+// Validate reports the first problem of a campaign
+func (c *Campaign) Validate() error {
+	if !idPattern.MatchString(c.ID) {
+		return fmt.Errorf("id: %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", c.ID)
+	}
+	start, err := parseDay(c.Start, false)
+	if err != nil {
+		return fmt.Errorf("start: %v", err)
+	}
+	end, err := parseDay(c.End, true)
+	if err != nil {
+		return fmt.Errorf("end: %v", err)
+	}
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		return fmt.Errorf("end: %s is before start %s", c.End, c.Start)
+	}
+	if c.SamplePercent < 0 || c.SamplePercent > 100 {
+		return fmt.Errorf("sample_percent: must be between 0 and 100, got %v", c.SamplePercent)
+	}
+	if c.SurveyVersion < 0 {
+		return fmt.Errorf("survey_version: must not be negative, got %d", c.SurveyVersion)
+	}
+	if err := checkPatterns("users", c.Audience.Users); err != nil {
+		return err
+	}
+	if err := checkPatterns("computers", c.Audience.Computers); err != nil {
+		return err
+	}
+	for i, t := range c.Audience.MachineTypes {
+		if t != sysinfo.Server && t != sysinfo.Workstation {
+			return fmt.Errorf("audience.machine_types[%d]: unknown machine type %q (expected %q or %q)", i, t, sysinfo.Server, sysinfo.Workstation)
+		}
+	}
+	return nil
+}
+
+// Active reports whether now falls in the campaign's window
+func (c *Campaign) Active(now time.Time) bool {
+	start, err := parseDay(c.Start, false)
+	if err != nil {
+		return false
+	}
+	end, err := parseDay(c.End, true)
+	if err != nil {
+		return false
+	}
+	if !start.IsZero() && now.Before(start) {
+		return false
+	}
+	if !end.IsZero() && !now.Before(end) {
+		return false
+	}
+	return true
+}
+
+// Targets reports whether the campaign's audience includes t
+func (c *Campaign) Targets(t Target) bool {
+	a := c.Audience
+	if len(a.Users) > 0 && !matchAny(a.Users, t.User) {
+		return false
+	}
+	if len(a.Computers) > 0 && !matchAny(a.Computers, t.Computer) {
+		return false
+	}
+	if len(a.MachineTypes) > 0 && !matchAny(a.MachineTypes, t.System[sysinfo.MachineType]) {
+		return false
+	}
+	return true
+}
+
+// Sample places t in the campaign's sample. bucket is a number in [0, 100)
+// derived from the user, computer and campaign id alone, so it is the same
+// on every logon; t is sampled when it is below sample_percent. Raising the
+// percentage later keeps everybody sampled before and adds new users.
+func (c *Campaign) Sample(t Target) (bucket float64, sampled bool) {
+	sum := sha256.Sum256([]byte(strings.ToLower(t.User) + "\x00" + strings.ToLower(t.Computer) + "\x00" + c.ID))
+	bucket = float64(binary.BigEndian.Uint64(sum[:8])%10000) / 100
+	if c.SamplePercent == 0 {
+		return bucket, true
+	}
+	return bucket, bucket < c.SamplePercent
+}
+
+// SampleStatus describes the sampling decision for t, for status output
+func (c *Campaign) SampleStatus(t Target) string {
+	if c.SamplePercent == 0 {
+		return "no sampling"
+	}
+	bucket, sampled := c.Sample(t)
+	if sampled {
+		return fmt.Sprintf("sampled (bucket %.2f < %v%%)", bucket, c.SamplePercent)
+	}
+	return fmt.Sprintf("not sampled (bucket %.2f >= %v%%)", bucket, c.SamplePercent)
+}
+
+// Select returns the first campaign that is active at now, targets and
+// samples t and asks the deployed question set version; nil if there is none
+func Select(campaigns []Campaign, now time.Time, t Target, surveyVersion int) *Campaign {
+	for i := range campaigns {
+		if campaigns[i].Why(now, t, surveyVersion) == "" {
+			return &campaigns[i]
+		}
+	}
+	return nil
+}
+
+// Why explains why a campaign is not asked of t at now, or returns "" if it is
+func (c *Campaign) Why(now time.Time, t Target, surveyVersion int) string {
+	switch {
+	case !c.Active(now):
+		return fmt.Sprintf("campaign %s is not running (start %q, end %q)", c.ID, c.Start, c.End)
+	case !c.Targets(t):
+		return fmt.Sprintf("campaign %s does not target %s on %s", c.ID, t.User, t.Computer)
+	case c.SurveyVersion != 0 && c.SurveyVersion != surveyVersion:
+		return fmt.Sprintf("campaign %s asks survey version %d, deployed is %d", c.ID, c.SurveyVersion, surveyVersion)
+	}
+	if _, sampled := c.Sample(t); !sampled {
+		return fmt.Sprintf("campaign %s: %s", c.ID, c.SampleStatus(t))
+	}
+	return ""
+}
+
+// parseDay reads a day or an RFC 3339 time. A day ends at the following
+// midnight when end is set, so "end" includes its last day.
+func parseDay(s string, end bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(DateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither YYYY-MM-DD nor an RFC 3339 time", s)
+	}
+	if end {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+func checkPatterns(name string, patterns []string) error {
+	for i, p := range patterns {
+		if _, err := path.Match(strings.ToLower(p), ""); err != nil || strings.TrimSpace(p) == "" {
+			return fmt.Errorf("audience.%s[%d]: %q is not a valid pattern", name, i, p)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, value string) bool {
+	value = strings.ToLower(value)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package campaign
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/sysinfo"
+)
+
+func TestActiveWindow(t *testing.T) {
+	c := Campaign{ID: "q3-2026", Start: "2026-07-01", End: "2026-09-30"}
+	for day, want := range map[string]bool{
+		"2026-06-30T23:59:00": false,
+		"2026-07-01T00:00:00": true,
+		"2026-09-30T23:59:00": true,
+		"2026-10-01T00:00:00": false,
+	} {
+		now, _ := time.ParseInLocation("2006-01-02T15:04:05", day, time.Local)
+		if got := c.Active(now); got != want {
+			t.Errorf("Active(%s) = %v, want %v", day, got, want)
+		}
+	}
+	if !(&Campaign{ID: "always"}).Active(time.Now()) {
+		t.Error("A campaign without a window should always be active")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	now, _ := time.ParseInLocation(DateLayout, "2026-08-15", time.Local)
+	campaigns := []Campaign{
+		{ID: "q2-2026", End: "2026-06-30"},
+		{ID: "servers", Audience: Audience{MachineTypes: []string{sysinfo.Server}, Computers: []string{"srv-*"}}},
+		{ID: "q3-2026", Start: "2026-07-01", End: "2026-09-30", SurveyVersion: 2},
+	}
+	server := Target{User: "alice", Computer: "SRV-01", System: sysinfo.Attributes{sysinfo.MachineType: sysinfo.Server}}
+	laptop := Target{User: "bob", Computer: "LT-17", System: sysinfo.Attributes{sysinfo.MachineType: sysinfo.Workstation}}
+
+	if c := Select(campaigns, now, server, 2); c == nil || c.ID != "servers" {
+		t.Errorf("Expected the server campaign for a server, got %+v", c)
+	}
+	if c := Select(campaigns, now, laptop, 2); c == nil || c.ID != "q3-2026" {
+		t.Errorf("Expected q3-2026 for a workstation, got %+v", c)
+	}
+	if c := Select(campaigns, now, laptop, 1); c != nil {
+		t.Errorf("No campaign asks survey version 1 of a workstation, got %+v", c)
+	}
+	if why := campaigns[2].Why(now, laptop, 1); !strings.Contains(why, "survey version 2") {
+		t.Errorf("Unexpected reason: %q", why)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, c := range map[string]Campaign{
+		"empty id":      {},
+		"space in id":   {ID: "Q3 2026"},
+		"path in id":    {ID: "../q3"},
+		"bad date":      {ID: "q3", Start: "01.07.2026"},
+		"end first":     {ID: "q3", Start: "2026-07-01", End: "2026-06-30"},
+		"bad pattern":   {ID: "q3", Audience: Audience{Users: []string{"[a"}}},
+		"machine type":  {ID: "q3", Audience: Audience{MachineTypes: []string{"laptop"}}},
+		"negative vers": {ID: "q3", SurveyVersion: -1},
+		"sample > 100":  {ID: "q3", SamplePercent: 150},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	c := Campaign{ID: "Q3-2026.satisfaction", Start: "2026-07-01", End: "2026-09-30T18:00:00+02:00", SurveyVersion: 2}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Valid campaign rejected: %v", err)
+	}
+}
+
+func TestSample(t *testing.T) {
+	c := Campaign{ID: "q3-2026", SamplePercent: 10}
+	wider := Campaign{ID: "q3-2026", SamplePercent: 25}
+	sampled := 0
+	for i := 0; i < 10000; i++ {
+		target := Target{User: fmt.Sprintf("user%d", i), Computer: "SRV-01"}
+		bucket, in := c.Sample(target)
+		if again, _ := c.Sample(Target{User: strings.ToUpper(target.User), Computer: "srv-01"}); again != bucket {
+			t.Fatalf("The bucket of %s should not depend on case: %v != %v", target.User, again, bucket)
+		}
+		if in {
+			sampled++
+			if _, stillIn := wider.Sample(target); !stillIn {
+				t.Errorf("%s was sampled at 10%% but not at 25%%", target.User)
+			}
+		}
+	}
+	if sampled < 900 || sampled > 1100 {
+		t.Errorf("Expected about 1000 of 10000 users sampled at 10%%, got %d", sampled)
+	}
+
+	// Each campaign draws its own sample
+	other := Campaign{ID: "q4-2026", SamplePercent: 10}
+	same := 0
+	for i := 0; i < 1000; i++ {
+		target := Target{User: fmt.Sprintf("user%d", i)}
+		a, _ := c.Sample(target)
+		b, _ := other.Sample(target)
+		if a == b {
+			same++
+		}
+	}
+	if same > 10 {
+		t.Errorf("Buckets should differ between campaigns, %d of 1000 are equal", same)
+	}
+
+	target := Target{User: "alice", Computer: "SRV-01"}
+	status := c.SampleStatus(target)
+	if _, in := c.Sample(target); in != strings.HasPrefix(status, "sampled") {
+		t.Errorf("Status %q does not match the sampling decision", status)
+	}
+	if (&Campaign{ID: "all"}).SampleStatus(target) != "no sampling" {
+		t.Error("A campaign without sample_percent asks everybody")
+	}
+}
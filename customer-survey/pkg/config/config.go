@@ -0,0 +1,430 @@
+// Package config resolves the survey configuration shared by every entry
+// point from layered sources. Later layers override earlier ones, one
+// top-level key at a time:
+//
+//  1. defaults  - embedded in the binary (config.json / build-time flags)
+//  2. machine   - first of: config next to the exe, configs\config next to
+//     the exe, %ProgramData%\CustomerSurvey\config, then config /
+//     configs\config in the working directory;
+//     Creator credentials from zoho_secure.json are part of this layer
+//  3. user      - %APPDATA%\CustomerSurvey\config
+//  4. env       - ZOHO_WEBHOOK_URL, SURVEY_DELIVERY_MODE and ZOHO_* credentials
+//
+// Each "config" is config.json, config.yaml or config.yml, tried in that
+// order. The webhook URL is accepted both as "webhook_url" and, from older
+// config files, as "zoho_webhook_url".
+//
+// "campaigns" lists runs of the survey. Each has its own done / no-thanks
+// / remind-later state per user (see pkg/campaign); without campaigns the
+// state from before campaigns existed is used. "cadence" limits how often
+// a user is asked (see startup.Policy), "prompt_timing" when within a
+// logon (see startup.Timing). "ephemeral_port" moves the local server of
+// the browser UI off its fixed ports.
+//
+// The survey itself is described by a separate definition file (see
+// pkg/definition): the path in "survey_definition", relative to the file
+// that sets it, or else the first survey.json / survey.yaml found in the
+// machine directories.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"customer-survey/pkg/appdata"
+	"customer-survey/pkg/campaign"
+	"customer-survey/pkg/definition"
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/sink"
+	"customer-survey/pkg/startup"
+	"customer-survey/pkg/yamljson"
+	"customer-survey/pkg/zoho"
+)
+
+// FileName is the name of the JSON configuration file in every file layer
+const FileName = "config.json"
+
+// FileNames are the configuration files looked for in each directory, in order
+var FileNames = []string{FileName, "config.yaml", "config.yml"}
+
+// Layers, lowest precedence first
+const (
+	LayerDefault = "default"
+	LayerMachine = "machine"
+	LayerUser    = "user"
+	LayerEnv     = "env"
+)
+
+// Config is the merged configuration
+type Config struct {
+	WebhookURL   string        `json:"webhook_url"`             // also read from "zoho_webhook_url"
+	DeliveryMode string        `json:"delivery_mode,omitempty"` // "webhook" (default) or "creator"
+	ZohoCreator  *zoho.Config  `json:"zoho_creator,omitempty"`
+	Retry        *retry.Config `json:"retry,omitempty"`
+	Sinks        []sink.Config `json:"sinks,omitempty"`
+
+	SurveyTimeout    int        `json:"survey_timeout,omitempty"`    // seconds, for every HTTP delivery
+	SurveyDefinition string     `json:"survey_definition,omitempty"` // path to survey.json / survey.yaml
+	Questions        []Question `json:"questions,omitempty"`         // prompt overrides, by position
+
+	Campaigns []campaign.Campaign `json:"campaigns,omitempty"` // the first running one that targets the user is asked
+	Cadence   *startup.Policy     `json:"cadence,omitempty"`   // how often a user may be asked
+
+	PromptTiming *startup.Timing `json:"prompt_timing,omitempty"` // when within a logon the prompt may appear
+
+	EphemeralPort bool `json:"ephemeral_port,omitempty"` // the browser UI listens on a port the OS picks instead of 8080-8090
+}
+
+// Question is one entry of the "questions" list. It rewords the question
+// at the same position of the survey definition; Scale, if set, switches a
+// rating or NPS question to the type of QuestionScales.
+type Question struct {
+	Question string `json:"question"`
+	Scale    int    `json:"scale,omitempty"`
+}
+
+// QuestionScales maps the "scale" of a configured question to the
+// question type that asks it: 3 is a Good/Okay/Bad rating, 10 a 0-10 NPS
+var QuestionScales = map[int]string{
+	3:  definition.TypeRating,
+	10: definition.TypeNPS,
+}
+
+// scaled reports whether a question's type can be switched by "scale"
+func scaled(q definition.Question) bool {
+	return q.Type == definition.TypeRating || q.Type == definition.TypeNPS
+}
+
+// apply returns d with its prompts and scales replaced by the configured
+// "questions", matched by position. Extra entries are ignored, and so is a
+// scale that QuestionScales does not know or that is set on a question
+// which is not a rating; Validate reports both.
+func (c *Config) apply(d *definition.Definition) *definition.Definition {
+	out := d.Copy()
+	for i, q := range c.Questions {
+		if i >= len(out.Questions) {
+			break
+		}
+		if strings.TrimSpace(q.Question) != "" {
+			out.Questions[i].Prompt = q.Question
+		}
+		if typ, ok := QuestionScales[q.Scale]; ok && scaled(out.Questions[i]) {
+			out.Questions[i].Type = typ
+			out.Questions[i].Scale = q.Scale
+		}
+	}
+	return out
+}
+
+// Timeout returns survey_timeout as a duration, or def when it is not set
+func (c *Config) Timeout(def time.Duration) time.Duration {
+	if c.SurveyTimeout > 0 {
+		return time.Duration(c.SurveyTimeout) * time.Second
+	}
+	return def
+}
+
+// Policy returns the "cadence" section; without it a user is asked until
+// they answer once or opt out
+func (c *Config) Policy() startup.Policy {
+	if c.Cadence == nil {
+		return startup.Policy{}
+	}
+	return *c.Cadence
+}
+
+// Timing returns the "prompt_timing" section; without it the prompt
+// appears right after logon
+func (c *Config) Timing() startup.Timing {
+	if c.PromptTiming == nil {
+		return startup.Timing{}
+	}
+	return *c.PromptTiming
+}
+
+// SinkConfigs returns the "sinks" list with survey_timeout filled in for
+// sinks that do not set their own timeout
+func (c *Config) SinkConfigs() []sink.Config {
+	out := make([]sink.Config, len(c.Sinks))
+	for i, sc := range c.Sinks {
+		if sc.TimeoutSeconds == 0 {
+			sc.TimeoutSeconds = c.SurveyTimeout
+		}
+		out[i] = sc
+	}
+	return out
+}
+
+// keyAliases maps legacy key spellings to the canonical key
+var keyAliases = map[string]string{
+	"zoho_webhook_url": "webhook_url",
+}
+
+// Source tells where a configuration value came from
+type Source struct {
+	Layer string
+	Path  string // file the value was read from, empty for defaults and env
+}
+
+func (s Source) String() string {
+	if s.Path == "" {
+		return s.Layer
+	}
+	return s.Layer + " (" + s.Path + ")"
+}
+
+// Loaded is the merged configuration together with its provenance
+type Loaded struct {
+	Config
+	Sources  map[string]Source // canonical top-level key -> layer it came from
+	Searched []string          // every file that was looked for, in order
+
+	unknown        []string // keys no field reads, reported by Validate
+	definitionPath string   // survey definition file, empty for definition.Default
+}
+
+// Definition returns the survey definition with the "questions" overrides
+// applied. Without a definition file it is definition.Default; if the file
+// cannot be loaded the error is returned together with the default.
+func (l *Loaded) Definition() (*definition.Definition, error) {
+	if l.definitionPath == "" {
+		return l.apply(&definition.Default), nil
+	}
+	d, err := definition.Load(l.definitionPath)
+	if err != nil {
+		return l.apply(&definition.Default), fmt.Errorf("survey_definition: %v", err)
+	}
+	return l.apply(d), nil
+}
+
+// Source returns where a top-level key was set; ok is false if no layer set it
+func (l *Loaded) Source(key string) (Source, bool) {
+	if canonical, ok := keyAliases[key]; ok {
+		key = canonical
+	}
+	s, ok := l.Sources[key]
+	return s, ok
+}
+
+// Report lists every configured key with its source, sorted by key.
+// Values are left out because they may contain secrets.
+func (l *Loaded) Report() []string {
+	keys := make([]string, 0, len(l.Sources))
+	for k := range l.Sources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %s", k, l.Sources[k]))
+	}
+	return lines
+}
+
+// Loader describes where each layer is read from
+type Loader struct {
+	Defaults        []byte   // JSON, may be empty
+	MachinePaths    []string // first existing file wins
+	SecretPaths     []string // zoho_secure.json candidates, first existing wins
+	UserPaths       []string // first existing file wins
+	DefinitionPaths []string // survey definition candidates when survey_definition is not set
+	Getenv          func(string) string
+}
+
+// Default returns the loader used by the applications
+func Default(defaults []byte) *Loader {
+	var dirs, secret []string
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		dirs = append(dirs, dir, filepath.Join(dir, "configs"))
+		secret = append(secret, filepath.Join(dir, zoho.SecureConfigFile), filepath.Join(dir, "configs", zoho.SecureConfigFile))
+	}
+	if programData := os.Getenv("ProgramData"); programData != "" {
+		dirs = append(dirs, filepath.Join(programData, "CustomerSurvey"))
+	}
+	// Working directory, for development
+	dirs = append(dirs, ".", "configs")
+	secret = append(secret, filepath.Join("configs", zoho.SecureConfigFile))
+
+	return &Loader{
+		Defaults:        defaults,
+		MachinePaths:    candidates(FileNames, dirs...),
+		SecretPaths:     secret,
+		UserPaths:       candidates(FileNames, appdata.Dir()),
+		DefinitionPaths: candidates(definition.FileNames, dirs...),
+		Getenv:          os.Getenv,
+	}
+}
+
+// candidates returns every one of names in each directory
+func candidates(names []string, dirs ...string) []string {
+	var paths []string
+	for _, dir := range dirs {
+		for _, name := range names {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// Load resolves the configuration with the default loader
+func Load(defaults []byte) (*Loaded, error) {
+	return Default(defaults).Load()
+}
+
+// Load merges every layer. It always returns a usable configuration; a
+// non-nil error reports files that could not be parsed and were skipped.
+func (l *Loader) Load() (*Loaded, error) {
+	merged := map[string]json.RawMessage{}
+	out := &Loaded{Sources: map[string]Source{}}
+	var problems []string
+
+	apply := func(values map[string]json.RawMessage, src Source) {
+		for k, v := range values {
+			merged[k] = v
+			out.Sources[k] = src
+		}
+	}
+
+	if len(l.Defaults) > 0 {
+		values, err := parse(l.Defaults, FileName)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("embedded defaults: %v", err))
+		} else {
+			out.unknown = append(out.unknown, unknownKeys(values, "embedded defaults")...)
+			apply(values, Source{Layer: LayerDefault})
+		}
+	}
+
+	file := func(layer string, paths []string, wrap func(map[string]json.RawMessage) map[string]json.RawMessage) {
+		for _, path := range paths {
+			out.Searched = append(out.Searched, path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			values, err := parse(data, path)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			if wrap != nil {
+				values = wrap(values)
+			}
+			out.unknown = append(out.unknown, unknownKeys(values, path)...)
+			abs, _ := filepath.Abs(path)
+			apply(values, Source{Layer: layer, Path: abs})
+			return
+		}
+	}
+
+	// zoho_secure.json holds {"zoho": {...}}; a zoho_creator section in
+	// config.json takes precedence over it
+	file(LayerMachine, l.SecretPaths, func(v map[string]json.RawMessage) map[string]json.RawMessage {
+		if creator, ok := v["zoho"]; ok {
+			return map[string]json.RawMessage{"zoho_creator": creator}
+		}
+		return nil
+	})
+	file(LayerMachine, l.MachinePaths, nil)
+	file(LayerUser, l.UserPaths, nil)
+
+	if l.Getenv != nil {
+		apply(l.env(), Source{Layer: LayerEnv})
+	}
+
+	data, _ := json.Marshal(merged)
+	if err := json.Unmarshal(data, &out.Config); err != nil {
+		problems = append(problems, err.Error())
+	}
+	out.resolveDefinition(l.DefinitionPaths)
+
+	if len(problems) > 0 {
+		return out, fmt.Errorf("config: skipped %s", strings.Join(problems, "; "))
+	}
+	return out, nil
+}
+
+// resolveDefinition picks the survey definition file: "survey_definition"
+// relative to the file that set it, or else the first existing candidate
+func (l *Loaded) resolveDefinition(candidates []string) {
+	if path := l.SurveyDefinition; path != "" {
+		if src, ok := l.Sources["survey_definition"]; ok && src.Path != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(src.Path), path)
+		}
+		l.definitionPath = path
+		return
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			abs, _ := filepath.Abs(path)
+			l.definitionPath = abs
+			l.Sources["survey_definition"] = Source{Layer: LayerMachine, Path: abs}
+			return
+		}
+	}
+}
+
+// parse reads one JSON or YAML object, depending on the file extension,
+// and renames legacy keys. Empty strings are dropped so that a blank
+// "webhook_url" does not hide a lower layer's value.
+func parse(data []byte, path string) (map[string]json.RawMessage, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		converted, err := yamljson.Convert(data)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	values := make(map[string]json.RawMessage, len(raw))
+	for k, v := range raw {
+		if s := strings.TrimSpace(string(v)); s == `""` || s == "null" {
+			continue
+		}
+		if canonical, ok := keyAliases[k]; ok {
+			// The canonical spelling wins if a file has both
+			if v, both := raw[canonical]; both && strings.TrimSpace(string(v)) != `""` {
+				continue
+			}
+			k = canonical
+		}
+		values[k] = v
+	}
+	return values, nil
+}
+
+// env returns the values set through environment variables
+func (l *Loader) env() map[string]json.RawMessage {
+	values := map[string]json.RawMessage{}
+	set := func(key, value string) {
+		if strings.TrimSpace(value) != "" {
+			values[key], _ = json.Marshal(value)
+		}
+	}
+	set("webhook_url", l.Getenv("ZOHO_WEBHOOK_URL"))
+	set("delivery_mode", l.Getenv("SURVEY_DELIVERY_MODE"))
+
+	creator := zoho.Config{
+		AccountOwner: l.Getenv("ZOHO_ACCOUNT_OWNER"),
+		AppLinkName:  l.Getenv("ZOHO_APP_LINK"),
+		FormLinkName: l.Getenv("ZOHO_FORM_LINK"),
+		ClientID:     l.Getenv("ZOHO_CLIENT_ID"),
+		ClientSecret: l.Getenv("ZOHO_CLIENT_SECRET"),
+		RefreshToken: l.Getenv("ZOHO_REFRESH_TOKEN"),
+		DataCenter:   l.Getenv("ZOHO_DATA_CENTER"),
+	}
+	// Credentials from the environment replace the whole section, but only when complete
+	if creator.ClientID != "" && creator.ClientSecret != "" && creator.RefreshToken != "" {
+		values["zoho_creator"], _ = json.Marshal(creator)
+	}
+	return values
+}
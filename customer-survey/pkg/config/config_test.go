@@ -0,0 +1,241 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/definition"
+	"customer-survey/pkg/sink"
+)
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func envMap(values map[string]string) func(string) string {
+	return func(k string) string { return values[k] }
+}
+
+func TestLayerPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	machine := writeFile(t, filepath.Join(dir, "machine", FileName),
+		`{"zoho_webhook_url": "https://machine.example.com", "delivery_mode": "webhook", "retry": {"max_attempts": 2}}`)
+	user := writeFile(t, filepath.Join(dir, "user", FileName), `{"delivery_mode": "creator"}`)
+
+	l := &Loader{
+		Defaults:     []byte(`{"zoho_webhook_url": "https://default.example.com", "retry": {"max_attempts": 9}}`),
+		MachinePaths: []string{filepath.Join(dir, "missing", FileName), machine},
+		UserPaths:    []string{user},
+		Getenv:       envMap(nil),
+	}
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.WebhookURL != "https://machine.example.com" {
+		t.Errorf("Machine file should override defaults, got %q", cfg.WebhookURL)
+	}
+	if cfg.DeliveryMode != "creator" {
+		t.Errorf("User file should override machine file, got %q", cfg.DeliveryMode)
+	}
+	if cfg.Retry.Policy().MaxAttempts != 2 {
+		t.Errorf("Expected retry from machine file, got %+v", cfg.Retry)
+	}
+	if src, _ := cfg.Source("zoho_webhook_url"); src.Layer != LayerMachine || filepath.Base(filepath.Dir(src.Path)) != "machine" {
+		t.Errorf("Unexpected source for webhook_url: %v", src)
+	}
+	if src, _ := cfg.Source("delivery_mode"); src.Layer != LayerUser {
+		t.Errorf("Unexpected source for delivery_mode: %v", src)
+	}
+	if len(cfg.Searched) != 3 {
+		t.Errorf("Expected every candidate up to the first hit to be searched, got %v", cfg.Searched)
+	}
+
+	l.Getenv = envMap(map[string]string{"ZOHO_WEBHOOK_URL": "https://env.example.com"})
+	cfg, _ = l.Load()
+	if cfg.WebhookURL != "https://env.example.com" {
+		t.Errorf("Environment should override files, got %q", cfg.WebhookURL)
+	}
+	if src, _ := cfg.Source("webhook_url"); src.Layer != LayerEnv {
+		t.Errorf("Unexpected source for webhook_url: %v", src)
+	}
+}
+
+func TestKeySpellingsAndBlankValues(t *testing.T) {
+	dir := t.TempDir()
+	both := writeFile(t, filepath.Join(dir, "both.json"),
+		`{"zoho_webhook_url": "https://old.example.com", "webhook_url": "https://new.example.com"}`)
+	blank := writeFile(t, filepath.Join(dir, "blank.json"), `{"webhook_url": ""}`)
+
+	cfg, err := (&Loader{MachinePaths: []string{both}, UserPaths: []string{blank}}).Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.WebhookURL != "https://new.example.com" {
+		t.Errorf("webhook_url should win over zoho_webhook_url, got %q", cfg.WebhookURL)
+	}
+	if src, _ := cfg.Source("webhook_url"); src.Layer != LayerMachine {
+		t.Errorf("A blank value should not override a lower layer, got source %v", src)
+	}
+}
+
+func TestCreatorCredentials(t *testing.T) {
+	dir := t.TempDir()
+	secure := writeFile(t, filepath.Join(dir, "zoho_secure.json"),
+		`{"zoho": {"account_owner": "acme", "client_id": "file-id", "client_secret": "s", "refresh_token": "r"}}`)
+
+	l := &Loader{SecretPaths: []string{secure}, Getenv: envMap(nil)}
+	cfg, err := l.Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.ZohoCreator == nil || cfg.ZohoCreator.ClientID != "file-id" {
+		t.Fatalf("zoho_secure.json should fill zoho_creator, got %+v", cfg.ZohoCreator)
+	}
+
+	// Incomplete credentials in the environment are ignored
+	l.Getenv = envMap(map[string]string{"ZOHO_CLIENT_ID": "env-id"})
+	if cfg, _ := l.Load(); cfg.ZohoCreator.ClientID != "file-id" {
+		t.Errorf("Incomplete env credentials should be ignored, got %q", cfg.ZohoCreator.ClientID)
+	}
+
+	l.Getenv = envMap(map[string]string{"ZOHO_CLIENT_ID": "env-id", "ZOHO_CLIENT_SECRET": "s", "ZOHO_REFRESH_TOKEN": "r"})
+	if cfg, _ := l.Load(); cfg.ZohoCreator.ClientID != "env-id" {
+		t.Errorf("Env credentials should win, got %q", cfg.ZohoCreator.ClientID)
+	}
+}
+
+func TestInvalidFileIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	broken := writeFile(t, filepath.Join(dir, "broken.json"), `{"webhook_url": `)
+	good := writeFile(t, filepath.Join(dir, "good.json"), `{"webhook_url": "https://good.example.com"}`)
+
+	cfg, err := (&Loader{MachinePaths: []string{broken, good}}).Load()
+	if err == nil {
+		t.Error("Expected an error for the unparseable file")
+	}
+	if cfg.WebhookURL != "https://good.example.com" {
+		t.Errorf("Expected the next candidate to be used, got %q", cfg.WebhookURL)
+	}
+}
+
+func TestYAMLConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, filepath.Join(dir, "config.yaml"), `
+zoho_webhook_url: "https://yaml.example.com"
+survey_timeout: 12
+retry:
+  max_attempts: 3
+questions:
+  - question: "How fast is the server?"
+    scale: 3
+  - question: "How likely are you to recommend us?"
+    scale: 10
+`)
+	cfg, err := (&Loader{MachinePaths: []string{filepath.Join(dir, FileName), path}}).Load()
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+	if cfg.WebhookURL != "https://yaml.example.com" || cfg.Retry.Policy().MaxAttempts != 3 {
+		t.Errorf("Unexpected YAML config: %+v", cfg.Config)
+	}
+	if cfg.Timeout(time.Minute) != 12*time.Second {
+		t.Errorf("survey_timeout should drive the HTTP timeout, got %v", cfg.Timeout(time.Minute))
+	}
+	if sinks := (&Config{SurveyTimeout: 12, Sinks: []sink.Config{{Type: sink.TypeFile}}}).SinkConfigs(); sinks[0].TimeoutSeconds != 12 {
+		t.Errorf("Sinks without a timeout should inherit survey_timeout, got %d", sinks[0].TimeoutSeconds)
+	}
+
+	d, err := cfg.Definition()
+	if err != nil {
+		t.Fatalf("Failed to resolve survey definition: %v", err)
+	}
+	if len(d.Questions) != len(definition.Default.Questions) || d.Questions[0].Prompt != "How fast is the server?" || d.Questions[2].Prompt != definition.Default.Questions[2].Prompt {
+		t.Errorf("Configured questions should reword the default survey by position, got %+v", d.Questions)
+	}
+	if d.Questions[0].Type != definition.TypeRating || d.Questions[1].Type != definition.TypeNPS || d.Questions[1].Scale != 10 {
+		t.Errorf("scale 10 should turn the question into an NPS question, got %+v", d.Questions[:2])
+	}
+	if err := d.Validate(); err != nil {
+		t.Errorf("The rescaled survey should be valid: %v", err)
+	}
+	if definition.Default.Questions[0].Prompt == "How fast is the server?" || definition.Default.Questions[1].Type != definition.TypeRating {
+		t.Error("Overrides must not change definition.Default")
+	}
+}
+
+func TestYAMLCampaignDays(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, filepath.Join(dir, "config.yaml"), `
+campaigns:
+  - id: q3
+    start: 2026-07-01
+    end: 2026-09-30
+`)
+	cfg, err := (&Loader{MachinePaths: []string{path}}).Load()
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+	c := cfg.Campaigns[0]
+	if c.Start != "2026-07-01" || c.End != "2026-09-30" {
+		t.Errorf("Unquoted days should be read as written, got start %q, end %q", c.Start, c.End)
+	}
+	if lastDay := time.Date(2026, 9, 30, 23, 0, 0, 0, time.Local); !c.Active(lastDay) {
+		t.Error("A campaign should run through its last day, local time")
+	}
+}
+
+func TestSurveyDefinitionFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "machine", "surveys", "it.yaml"), `
+id: it-survey
+version: 3
+questions:
+  - {key: speed, type: rating, prompt: "Speed?", required: true}
+  - {key: comment, type: text, prompt: "Comments?"}
+`)
+	machine := writeFile(t, filepath.Join(dir, "machine", FileName), `{"survey_definition": "surveys/it.yaml"}`)
+	found := writeFile(t, filepath.Join(dir, "found", "survey.json"),
+		`{"id": "found", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A"}]}`)
+
+	cfg, err := (&Loader{MachinePaths: []string{machine}, DefinitionPaths: []string{found}}).Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	d, err := cfg.Definition()
+	if err != nil {
+		t.Fatalf("Failed to load survey definition: %v", err)
+	}
+	if d.ID != "it-survey" || d.Version != 3 || len(d.Questions) != 2 {
+		t.Errorf("survey_definition should be resolved next to config.json, got %+v", d)
+	}
+
+	// Without survey_definition the first definition file found is used
+	cfg, _ = (&Loader{DefinitionPaths: []string{filepath.Join(dir, "missing.json"), found}}).Load()
+	if d, _ := cfg.Definition(); d.ID != "found" {
+		t.Errorf("Expected the searched definition, got %q", d.ID)
+	}
+	if src, ok := cfg.Source("survey_definition"); !ok || filepath.Base(src.Path) != "survey.json" {
+		t.Errorf("Unexpected source for survey_definition: %v", src)
+	}
+
+	// A broken definition falls back to the default survey but is reported
+	broken := writeFile(t, filepath.Join(dir, "broken", "survey.json"), `{"id": "x", "version": 1, "questions": []}`)
+	cfg, _ = (&Loader{DefinitionPaths: []string{broken}}).Load()
+	if d, err := cfg.Definition(); err == nil || d.ID != definition.Default.ID {
+		t.Errorf("Expected the default survey and an error, got %q, %v", d.ID, err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "survey_definition") {
+		t.Errorf("Validate should report the broken definition, got %v", err)
+	}
+}
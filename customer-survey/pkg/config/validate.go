@@ -0,0 +1,344 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/sink"
+	"customer-survey/pkg/startup"
+)
+
+// DataCenters are the Zoho data center domains accepted in "data_center"
+var DataCenters = []string{"com", "eu", "in", "com.au", "jp", "ca", "uk", "sa", "com.cn"}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// Validate checks the merged configuration, including keys that no field
+// understands in any of the files it was read from, and the machine policy
+func (l *Loaded) Validate() error {
+	problems := append([]string(nil), l.unknown...)
+	if _, err := startup.LoadMachinePolicy(); err != nil {
+		problems = append(problems, fmt.Sprintf("machine policy: %v (nobody is asked until it is fixed)", err))
+	}
+	if err := l.Config.Validate(); err != nil {
+		problems = append(problems, err.(*ValidationError).Problems...)
+	}
+	d, err := l.Definition()
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if len(l.Questions) > len(d.Questions) {
+		problems = append(problems, fmt.Sprintf("questions: the survey asks %d questions, got %d", len(d.Questions), len(l.Questions)))
+	}
+	if err == nil {
+		for i, q := range l.Questions {
+			if q.Scale != 0 && i < len(d.Questions) && !scaled(d.Questions[i]) {
+				problems = append(problems, fmt.Sprintf("questions[%d].scale: question %q is a %s question and has no scale", i, d.Questions[i].Key, d.Questions[i].Type))
+			}
+		}
+	}
+	if err == nil {
+		for i, c := range l.Campaigns {
+			if c.SurveyVersion != 0 && c.SurveyVersion != d.Version {
+				problems = append(problems, fmt.Sprintf("campaigns[%d].survey_version: %d is not the deployed survey version %d", i, c.SurveyVersion, d.Version))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks values that parse but cannot work: malformed URLs,
+// unknown delivery modes or Zoho data centers, unsupported rating scales,
+// negative timeouts or cadence limits, malformed campaigns and prompt times
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.WebhookURL != "" {
+		if err := checkURL(c.WebhookURL); err != nil {
+			add("webhook_url: %v", err)
+		}
+	}
+	switch strings.ToLower(c.DeliveryMode) {
+	case "", sink.ModeWebhook, sink.ModeCreator:
+	default:
+		add("delivery_mode: unknown mode %q (expected %q or %q)", c.DeliveryMode, sink.ModeWebhook, sink.ModeCreator)
+	}
+	if c.ZohoCreator != nil && c.ZohoCreator.DataCenter != "" && !knownDataCenter(c.ZohoCreator.DataCenter) {
+		add("zoho_creator.data_center: unknown data center %q (expected one of %s)", c.ZohoCreator.DataCenter, strings.Join(DataCenters, ", "))
+	}
+	if c.SurveyTimeout < 0 {
+		add("survey_timeout: must not be negative, got %d", c.SurveyTimeout)
+	}
+	if c.Retry != nil {
+		problems = append(problems, checkRetry("retry", c.Retry.MaxAttempts, c.Retry.BaseDelayMS, c.Retry.MaxDelayMS, c.Retry.Jitter)...)
+	}
+	for i, q := range c.Questions {
+		if strings.TrimSpace(q.Question) == "" {
+			add("questions[%d].question: is empty", i)
+		}
+		if _, ok := QuestionScales[q.Scale]; q.Scale != 0 && !ok {
+			add("questions[%d].scale: unsupported scale %d (3 asks Good/Okay/Bad, 10 asks 0-10)", i, q.Scale)
+		}
+	}
+	if c.Cadence != nil {
+		if err := c.Cadence.Validate(); err != nil {
+			add("cadence.%v", err)
+		}
+	}
+	if c.PromptTiming != nil {
+		if err := c.PromptTiming.Validate(); err != nil {
+			add("prompt_timing.%v", err)
+		}
+	}
+	ids := map[string]bool{}
+	for i, camp := range c.Campaigns {
+		if err := camp.Validate(); err != nil {
+			add("campaigns[%d].%v", i, err)
+		} else if ids[camp.ID] {
+			add("campaigns[%d].id: %q is used twice", i, camp.ID)
+		}
+		ids[camp.ID] = true
+	}
+
+	sinkNames := map[string]bool{}
+	for i, sc := range c.Sinks {
+		prefix := fmt.Sprintf("sinks[%d]", i)
+		if sc.TimeoutSeconds < 0 {
+			add("%s.timeout_seconds: must not be negative, got %d", prefix, sc.TimeoutSeconds)
+		}
+		if sc.Creator != nil && sc.Creator.DataCenter != "" && !knownDataCenter(sc.Creator.DataCenter) {
+			add("%s.creator.data_center: unknown data center %q", prefix, sc.Creator.DataCenter)
+		}
+		if sc.Retry != nil {
+			problems = append(problems, checkRetry(prefix+".retry", sc.Retry.MaxAttempts, sc.Retry.BaseDelayMS, sc.Retry.MaxDelayMS, sc.Retry.Jitter)...)
+		}
+		// Per-type checks (missing url/path, field mapping) live in sink.New
+		s, err := sink.New(sc, retry.DefaultPolicy)
+		if err != nil {
+			add("%s: %v", prefix, err)
+			continue
+		}
+		// The outbox tracks which sinks accepted a response by name
+		if sinkNames[s.Name()] {
+			add("%s.name: %q is used twice", prefix, s.Name())
+		}
+		sinkNames[s.Name()] = true
+		if strings.EqualFold(sc.Type, sink.TypeWebhook) {
+			if err := checkURL(sc.URL); err != nil {
+				add("%s.url: %v", prefix, err)
+			}
+		}
+	}
+
+	if len(c.Sinks) > 0 && !sink.HasRequired(c.Sinks) {
+		add("sinks: every sink is optional; at least one must be required, or responses nobody accepted count as sent")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// CheckFile validates a single config file (JSON or YAML) on its own, as
+// admins do before packaging it
+func CheckFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	values, err := parse(data, path)
+	if err != nil {
+		return &ValidationError{Problems: []string{fmt.Sprintf("%s: %v", path, err)}}
+	}
+	out := &Loaded{Sources: map[string]Source{}, unknown: unknownKeys(values, path)}
+	abs, _ := filepath.Abs(path)
+	for k := range values {
+		out.Sources[k] = Source{Layer: LayerMachine, Path: abs}
+	}
+	merged, _ := json.Marshal(values)
+	if err := json.Unmarshal(merged, &out.Config); err != nil {
+		out.unknown = append(out.unknown, fmt.Sprintf("%s: %v", path, err))
+	}
+	out.resolveDefinition(nil)
+	return out.Validate()
+}
+
+// RunValidate implements the "validate-config" subcommand. With no
+// arguments it checks the configuration the app would load on top of
+// defaults and the machine policy; otherwise each file is checked on its
+// own, a policy.json as a machine policy. It returns the process exit code.
+func RunValidate(defaults []byte, args []string, w io.Writer) int {
+	failed := false
+	report := func(name string, err error) {
+		if err == nil {
+			fmt.Fprintf(w, "OK    %s\n", name)
+			return
+		}
+		failed = true
+		fmt.Fprintf(w, "ERROR %s\n", name)
+		if ve, ok := err.(*ValidationError); ok {
+			for _, p := range ve.Problems {
+				fmt.Fprintf(w, "  - %s\n", p)
+			}
+		} else {
+			fmt.Fprintf(w, "  - %v\n", err)
+		}
+	}
+
+	if len(args) == 0 {
+		loaded, err := Load(defaults)
+		if err != nil {
+			report("merged configuration", err)
+		}
+		for _, line := range loaded.Report() {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+		report("merged configuration", loaded.Validate())
+	}
+	for _, path := range args {
+		if strings.EqualFold(filepath.Base(path), startup.MachinePolicyFile) {
+			_, err := startup.ReadMachinePolicy(path)
+			report(path, err)
+			continue
+		}
+		report(path, CheckFile(path))
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// unknownKeys reports keys of a parsed file that no Config field reads
+func unknownKeys(values map[string]json.RawMessage, path string) []string {
+	var out []string
+	for k, raw := range values {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			continue
+		}
+		walkKeys(map[string]interface{}{k: v}, reflect.TypeOf(Config{}), "", func(key string) {
+			out = append(out, fmt.Sprintf("%s: unknown key %q", path, key))
+		})
+	}
+	sort.Strings(out)
+	return out
+}
+
+// walkKeys compares a decoded JSON value against the json tags of t
+func walkKeys(v interface{}, t reflect.Type, prefix string, unknown func(string)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		fields := jsonFields(t)
+		for k, child := range val {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				unknown(name)
+				continue
+			}
+			walkKeys(child, ft, name, unknown)
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return
+		}
+		for i, child := range val {
+			walkKeys(child, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), unknown)
+		}
+	}
+}
+
+// jsonFields maps json names to field types, following embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must start with http:// or https://", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
+
+func checkRetry(prefix string, attempts, base, max int, jitter *float64) []string {
+	var problems []string
+	if attempts < 0 {
+		problems = append(problems, fmt.Sprintf("%s.max_attempts: must not be negative, got %d", prefix, attempts))
+	}
+	if base < 0 {
+		problems = append(problems, fmt.Sprintf("%s.base_delay_ms: must not be negative, got %d", prefix, base))
+	}
+	if max < 0 {
+		problems = append(problems, fmt.Sprintf("%s.max_delay_ms: must not be negative, got %d", prefix, max))
+	}
+	if jitter != nil && (*jitter < 0 || *jitter > 1) {
+		problems = append(problems, fmt.Sprintf("%s.jitter: must be between 0 and 1, got %v", prefix, *jitter))
+	}
+	return problems
+}
+
+func knownDataCenter(dc string) bool {
+	for _, known := range DataCenters {
+		if strings.EqualFold(dc, known) {
+			return true
+		}
+	}
+	return false
+}
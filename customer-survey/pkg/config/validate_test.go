@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"customer-survey/pkg/startup"
+)
+
+func TestCheckFileReportsEveryProblem(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), FileName), `{
+  "zoho_webhook_url": "flow.zoho.in/webhook",
+  "delivery_mode": "carrier-pigeon",
+  "survey_timout": 10,
+  "survey_timeout": -1,
+  "zoho_creator": {"data_center": "zoho.in", "client_secrt": "x"},
+  "retry": {"jitter": 2},
+  "questions": [{"question": "Speed?", "scale": 7}],
+  "cadence": {"cooldown_days": -90},
+  "prompt_timing": {"weekdays": ["mon", "funday"]},
+  "campaigns": [{"id": "Q3 2026"}, {"id": "q2", "start": "2026-07-01", "end": "2026-06-30"}, {"id": "q4", "survey_version": 2}],
+  "sinks": [{"type": "webhook", "url": "https://", "timeout_seconds": -5, "fields": [{"key": "a", "field": "note", "typ": "string"}]}]
+}`)
+
+	err := CheckFile(path)
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	for _, want := range []string{
+		`unknown key "survey_timout"`,
+		`unknown key "zoho_creator.client_secrt"`,
+		`unknown key "sinks[0].fields[0].typ"`,
+		"webhook_url",
+		"delivery_mode",
+		"zoho_creator.data_center",
+		"survey_timeout: must not be negative",
+		"retry.jitter",
+		"questions[0].scale",
+		"cadence.cooldown_days",
+		`prompt_timing.weekdays[1]: unknown day "funday"`,
+		"campaigns[0].id",
+		"campaigns[1].end",
+		"campaigns[2].survey_version: 2 is not the deployed survey version 1",
+		"sinks[0].timeout_seconds",
+		"sinks[0].url",
+	} {
+		if !strings.Contains(ve.Error(), want) {
+			t.Errorf("Expected a problem mentioning %q, got:\n%s", want, strings.Join(ve.Problems, "\n"))
+		}
+	}
+}
+
+func TestCheckFileAcceptsShippedConfigs(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"legacy.json": `{"zoho_webhook_url": "https://flow.zoho.in/123/flow/webhook/incoming?zapikey=abc"}`,
+		"config.yaml": "webhook_url: https://flow.zoho.in/hook\nsurvey_timeout: 10\nquestions:\n  - question: Speed?\n    scale: 3\ncadence:\n  cooldown_days: 90\n  max_ignored_prompts: 3\n",
+		"creator.json": `{"delivery_mode": "creator", "zoho_creator": {"account_owner": "acme", "app_link_name": "a",
+			"form_link_name": "f", "client_id": "i", "client_secret": "s", "refresh_token": "r", "data_center": "com.au"}}`,
+	} {
+		if err := CheckFile(writeFile(t, filepath.Join(dir, name), content)); err != nil {
+			t.Errorf("%s should be valid: %v", name, err)
+		}
+	}
+	if err := CheckFile(filepath.Join("..", "..", "configs", "config.yaml")); err != nil {
+		t.Errorf("configs/config.yaml should be valid: %v", err)
+	}
+}
+
+func TestQuestionScaleNeedsARating(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), FileName), `{"questions": [
+  {"question": "Speed?", "scale": 10}, {"question": "Support?"}, {"question": "Overall?"},
+  {"question": "Anything else?", "scale": 3}
+]}`)
+
+	err := CheckFile(path)
+	ve, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+	if len(ve.Problems) != 1 || !strings.Contains(ve.Problems[0], "questions[3].scale") {
+		t.Errorf("Only the scale on the text question should be reported, got:\n%s", strings.Join(ve.Problems, "\n"))
+	}
+}
+
+func TestValidateReportsMachinePolicy(t *testing.T) {
+	dir := t.TempDir()
+	policy := writeFile(t, filepath.Join(dir, startup.MachinePolicyFile), `{"disabled": "yes"}`)
+	t.Setenv(startup.MachinePolicyEnv, policy)
+
+	loaded, err := (&Loader{MachinePaths: []string{filepath.Join(dir, FileName)}}).Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := loaded.Validate(); err == nil || !strings.Contains(err.Error(), "machine policy") {
+		t.Errorf("A malformed machine policy should be reported, got %v", err)
+	}
+
+	var out bytes.Buffer
+	if code := RunValidate(nil, []string{policy}, &out); code != 1 || !strings.Contains(out.String(), "disabled") {
+		t.Errorf("validate-config should check policy.json as a machine policy, got %d:\n%s", code, out.String())
+	}
+	writeFile(t, policy, `{"disabled": true}`)
+	if code := RunValidate(nil, []string{policy}, &out); code != 0 {
+		t.Errorf("A valid machine policy should pass, got %d", code)
+	}
+}
+
+func TestSinksNeedARequiredOne(t *testing.T) {
+	path := writeFile(t, filepath.Join(t.TempDir(), FileName),
+		`{"sinks": [{"type": "webhook", "url": "https://flow.zoho.in/hook", "required": false}]}`)
+	if err := CheckFile(path); err == nil || !strings.Contains(err.Error(), "at least one must be required") {
+		t.Errorf("Sinks that are all optional should be reported, got %v", err)
+	}
+}
+
+func TestRunValidateExitCode(t *testing.T) {
+	dir := t.TempDir()
+	good := writeFile(t, filepath.Join(dir, "good.json"), `{"webhook_url": "https://flow.zoho.in/hook"}`)
+	bad := writeFile(t, filepath.Join(dir, "bad.json"), `{"webhook": "https://flow.zoho.in/hook"}`)
+
+	var out bytes.Buffer
+	if code := RunValidate(nil, []string{good}, &out); code != 0 {
+		t.Errorf("Expected exit code 0, got %d:\n%s", code, out.String())
+	}
+	out.Reset()
+	if code := RunValidate(nil, []string{good, bad}, &out); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(out.String(), `unknown key "webhook"`) {
+		t.Errorf("Output should list the problem, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,131 @@
+package definition
+
+import (
+	"fmt"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/sysinfo"
+)
+
+// Condition is one entry of a question's "show_if" list. It tests either
+// the answer to an earlier question or a system attribute (see pkg/sysinfo)
+// with exactly one of equals, not_equals or in. Values are compared as
+// text, so 1 and "1" match; a multi_choice answer matches if any chosen
+// option does. A condition on a question that was not answered is false.
+type Condition struct {
+	Answer    string        `json:"answer,omitempty"` // key of an earlier question
+	System    string        `json:"system,omitempty"` // e.g. "machine_type"
+	Equals    interface{}   `json:"equals,omitempty"`
+	NotEquals interface{}   `json:"not_equals,omitempty"`
+	In        []interface{} `json:"in,omitempty"`
+}
+
+// Shows reports whether q is asked, given the answers so far and the
+// system attributes. Every condition in show_if must hold.
+func (q Question) Shows(answers map[string]interface{}, system sysinfo.Attributes) bool {
+	for _, c := range q.ShowIf {
+		if !c.holds(answers, system) {
+			return false
+		}
+	}
+	return true
+}
+
+// Visible returns the keys of the questions asked, in order. A question
+// hidden by its conditions does not count as answered for later ones.
+func (d *Definition) Visible(answers map[string]interface{}, system sysinfo.Attributes) []string {
+	shown := map[string]interface{}{}
+	keys := []string{}
+	for _, q := range d.Questions {
+		if !q.Shows(shown, system) {
+			continue
+		}
+		keys = append(keys, q.Key)
+		if v, ok := answers[q.Key]; ok {
+			shown[q.Key] = v
+		}
+	}
+	return keys
+}
+
+func (c Condition) holds(answers map[string]interface{}, system sysinfo.Attributes) bool {
+	var values []string
+	if c.System != "" {
+		v, ok := system[c.System]
+		if !ok {
+			return false
+		}
+		values = []string{v}
+	} else {
+		v, ok := answers[c.Answer]
+		if !ok || empty(v) {
+			return false
+		}
+		switch list := v.(type) {
+		case []interface{}:
+			for _, item := range list {
+				values = append(values, model.Text(item))
+			}
+		case []string:
+			values = list
+		default:
+			values = []string{model.Text(v)}
+		}
+	}
+
+	matches := func(want interface{}) bool {
+		for _, v := range values {
+			if v == model.Text(want) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case c.Equals != nil:
+		return matches(c.Equals)
+	case c.NotEquals != nil:
+		return !matches(c.NotEquals)
+	default:
+		for _, want := range c.In {
+			if matches(want) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// validate checks that a condition can be evaluated; earlier holds the
+// keys of the questions before the one it belongs to
+func (c Condition) validate(earlier map[string]bool) error {
+	switch {
+	case c.Answer != "" && c.System != "":
+		return fmt.Errorf("use either answer or system, not both")
+	case c.Answer != "":
+		if !earlier[c.Answer] {
+			return fmt.Errorf("answer: %q is not an earlier question", c.Answer)
+		}
+	case c.System != "":
+		if !sysinfo.Known(c.System) {
+			return fmt.Errorf("system: unknown attribute %q (known: %v)", c.System, sysinfo.Names)
+		}
+	default:
+		return fmt.Errorf("needs answer or system")
+	}
+
+	operators := 0
+	if c.Equals != nil {
+		operators++
+	}
+	if c.NotEquals != nil {
+		operators++
+	}
+	if len(c.In) > 0 {
+		operators++
+	}
+	if operators != 1 {
+		return fmt.Errorf("needs exactly one of equals, not_equals or in")
+	}
+	return nil
+}
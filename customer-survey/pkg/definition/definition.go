@@ -0,0 +1,218 @@
+// Package definition describes which questions a survey asks. A definition
+// is read from survey.json / survey.yaml, served to every UI and stamped on
+// each response, so adding or rewording a question needs no code release.
+package definition
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/sysinfo"
+	"customer-survey/pkg/yamljson"
+)
+
+// FileNames are the definition files looked for in each config directory, in order
+var FileNames = []string{"survey.json", "survey.yaml", "survey.yml"}
+
+// Definition is one version of a survey
+type Definition struct {
+	ID        string     `json:"id"`
+	Version   int        `json:"version"`
+	Title     string     `json:"title,omitempty"`
+	Questions []Question `json:"questions"`
+}
+
+// Question is one entry of the ordered "questions" list. Key names the
+// answer in the response's answer map; the settings used depend on Type.
+type Question struct {
+	Key       string   `json:"key"`
+	Type      string   `json:"type"`
+	Prompt    string   `json:"prompt"`
+	Scale     int      `json:"scale,omitempty"`      // highest value of rating, nps, likert and stars
+	Options   []string `json:"options,omitempty"`    // choice and multi_choice
+	MaxLength int      `json:"max_length,omitempty"` // text
+	Required  bool     `json:"required,omitempty"`   // only while the question is shown
+
+	ShowIf []Condition `json:"show_if,omitempty"` // asked only when every condition holds
+}
+
+// Default is the survey asked when no definition file is deployed: the
+// three rating questions and the optional comment every UI used to hard-code
+var Default = Definition{
+	ID:      "customer-survey",
+	Version: 1,
+	Questions: []Question{
+		{Key: model.KeyServerPerformance, Type: TypeRating, Prompt: "Server Experience", Scale: 3, Required: true},
+		{Key: model.KeyTechnicalSupport, Type: TypeRating, Prompt: "Technical Support", Scale: 3, Required: true},
+		{Key: model.KeyOverallSupport, Type: TypeRating, Prompt: "Overall Rating", Scale: 3, Required: true},
+		{Key: model.KeyNote, Type: TypeText, Prompt: "Additional Feedback? (optional)", MaxLength: DefaultMaxLength},
+	},
+}
+
+// Load reads a JSON or YAML definition, depending on the file extension,
+// and validates it
+func Load(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		if data, err = yamljson.Convert(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	var d Definition
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for i := range d.Questions {
+		d.Questions[i].normalize()
+	}
+	if err := d.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &d, nil
+}
+
+// Copy returns a definition whose question list can be changed without
+// affecting d
+func (d *Definition) Copy() *Definition {
+	out := *d
+	out.Questions = append([]Question(nil), d.Questions...)
+	return &out
+}
+
+// Validate reports the first problem that would stop the UIs from showing
+// the survey or the answers from being stored
+func (d *Definition) Validate() error {
+	if strings.TrimSpace(d.ID) == "" {
+		return fmt.Errorf("id is empty")
+	}
+	if d.Version < 1 {
+		return fmt.Errorf("version must be 1 or higher, got %d", d.Version)
+	}
+	if len(d.Questions) == 0 {
+		return fmt.Errorf("questions: list is empty")
+	}
+	keys := map[string]bool{}
+	for i, q := range d.Questions {
+		if strings.TrimSpace(q.Key) == "" {
+			return fmt.Errorf("questions[%d].key: is empty", i)
+		}
+		if keys[q.Key] {
+			return fmt.Errorf("questions[%d].key: %q is used twice", i, q.Key)
+		}
+		for j, c := range q.ShowIf {
+			if err := c.validate(keys); err != nil {
+				return fmt.Errorf("questions[%d].show_if[%d]: %v", i, j, err)
+			}
+		}
+		keys[q.Key] = true
+		if strings.TrimSpace(q.Prompt) == "" {
+			return fmt.Errorf("questions[%d].prompt: is empty", i)
+		}
+		if err := q.validate(); err != nil {
+			return fmt.Errorf("questions[%d].%v", i, err)
+		}
+	}
+	return nil
+}
+
+// Question returns the question with the given key
+func (d *Definition) Question(key string) (Question, bool) {
+	for _, q := range d.Questions {
+		if q.Key == key {
+			return q, true
+		}
+	}
+	return Question{}, false
+}
+
+// Check validates a completed survey: every answer must belong to a
+// question and fit its type, and every required question that is shown
+// must be answered. Answers to questions hidden by their conditions are
+// dropped. It returns the answers in their stored form, or an *AnswerError.
+func (d *Definition) Check(in map[string]interface{}, system sysinfo.Attributes) (model.Answers, error) {
+	return d.check(in, system, true)
+}
+
+// Answers validates the answers sent with a response that is not a
+// completed survey, such as a reminder carrying only a note. It is Check
+// without required questions; the note the UIs send with every reminder
+// and decline is dropped when the survey asks no model.KeyNote question.
+func (d *Definition) Answers(in map[string]interface{}, system sysinfo.Attributes) (model.Answers, error) {
+	return d.check(in, system, false)
+}
+
+// check implements Check and Answers; required says whether required
+// questions must be answered
+func (d *Definition) check(in map[string]interface{}, system sysinfo.Attributes, required bool) (model.Answers, error) {
+	var problems []string
+	for key := range in {
+		if _, ok := d.Question(key); !ok && (required || key != model.KeyNote) {
+			problems = append(problems, fmt.Sprintf("%s: not a question of survey %s v%d", key, d.ID, d.Version))
+		}
+	}
+	out := model.Answers{}
+	for _, q := range d.Questions {
+		if !q.Shows(out, system) {
+			continue
+		}
+		v, err := q.Check(in[q.Key])
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", q.Key, err))
+		case v != nil:
+			out[q.Key] = v
+		case q.Required && required:
+			problems = append(problems, fmt.Sprintf("%s: an answer is required", q.Key))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &AnswerError{Problems: problems}
+	}
+	return out, nil
+}
+
+// Labels returns the label of every labelled answer, by question key
+func (d *Definition) Labels(answers model.Answers) map[string]string {
+	labels := map[string]string{}
+	for _, q := range d.Questions {
+		if v, ok := answers[q.Key]; ok {
+			if l := q.Label(v); l != "" {
+				labels[q.Key] = l
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// Stamp records which survey and version resp answers, and the labels of
+// its answers so sinks can send them without the definition
+func (d *Definition) Stamp(resp *model.SurveyResponse) {
+	resp.SurveyID = d.ID
+	resp.SurveyVersion = d.Version
+	resp.Labels = d.Labels(resp.Answers)
+}
+
+// KnownScale reports whether the UIs can show a rating scale
+func KnownScale(scale int) bool {
+	for _, s := range RatingScales {
+		if s == scale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package definition
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/sysinfo"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default.Validate(); err != nil {
+		t.Fatalf("Default definition should be valid: %v", err)
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeFile(t, "survey.yaml", `
+id: it-survey
+version: 2
+questions:
+  - key: speed
+    type: rating
+    prompt: How fast is the server?
+    required: true
+  - key: wishes
+    type: text
+    prompt: Anything else?
+`)
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load definition: %v", err)
+	}
+	if d.ID != "it-survey" || d.Version != 2 || len(d.Questions) != 2 {
+		t.Fatalf("Unexpected definition: %+v", d)
+	}
+	if q, _ := d.Question("speed"); q.Scale != 3 || !q.Required {
+		t.Errorf("Rating questions should default to scale 3, got %+v", q)
+	}
+}
+
+func TestLoadRejectsInvalidDefinitions(t *testing.T) {
+	for name, content := range map[string]string{
+		"duplicate key": `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A"}, {"key": "a", "type": "text", "prompt": "B"}]}`,
+		"unknown type":  `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "slider", "prompt": "A"}]}`,
+		"bad scale":     `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "rating", "prompt": "A", "scale": 10}]}`,
+		"no version":    `{"id": "s", "questions": [{"key": "a", "type": "text", "prompt": "A"}]}`,
+		"unknown field": `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "promt": "A"}]}`,
+		"one option":    `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "choice", "prompt": "A", "options": ["x"]}]}`,
+		"nps scale":     `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "nps", "prompt": "A", "scale": 5}]}`,
+		"many stars":    `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "stars", "prompt": "A", "scale": 20}]}`,
+		"long text":     `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A", "max_length": 100000}]}`,
+		"stray options": `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "stars", "prompt": "A", "options": ["x", "y"]}]}`,
+	} {
+		if _, err := Load(writeFile(t, "survey.json", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		} else if !strings.Contains(err.Error(), "survey.json") {
+			t.Errorf("%s: error should name the file, got %v", name, err)
+		}
+	}
+}
+
+func TestCheckAnswers(t *testing.T) {
+	d, err := Load(writeFile(t, "survey.json", `{"id": "typed", "version": 1, "questions": [
+		{"key": "nps", "type": "nps", "prompt": "Recommend us?", "required": true},
+		{"key": "agree", "type": "likert", "prompt": "Servers are fast", "required": true},
+		{"key": "stars", "type": "stars", "prompt": "Rate the helpdesk"},
+		{"key": "os", "type": "choice", "prompt": "Which OS?", "options": ["Windows", "Linux"]},
+		{"key": "apps", "type": "multi_choice", "prompt": "Which apps?", "options": ["Office", "SAP", "Teams"]},
+		{"key": "comment", "type": "text", "prompt": "Anything else?", "max_length": 10}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to load definition: %v", err)
+	}
+
+	// Answers as they arrive from the browser, decoded from JSON
+	var in map[string]interface{}
+	json.Unmarshal([]byte(`{"nps": 9, "agree": 4, "stars": 5, "os": "Linux", "apps": ["SAP", "Office", "SAP"], "comment": "  quick  "}`), &in)
+	answers, err := d.Check(in, nil)
+	if err != nil {
+		t.Fatalf("Valid answers rejected: %v", err)
+	}
+	if answers["nps"] != 9 || answers["comment"] != "quick" || len(answers["apps"].([]string)) != 2 {
+		t.Errorf("Unexpected stored answers: %#v", answers)
+	}
+	labels := d.Labels(answers)
+	if labels["nps"] != "Promoter" || labels["agree"] != "Agree" || labels["stars"] != "5/5" || labels["apps"] != "SAP, Office" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+
+	in = nil
+	json.Unmarshal([]byte(`{"nps": 11, "stars": 2.5, "os": "Mac", "apps": "SAP", "comment": "far too long", "extra": 1}`), &in)
+	_, err = d.Check(in, nil)
+	ae, ok := err.(*AnswerError)
+	if !ok {
+		t.Fatalf("Expected an AnswerError, got %v", err)
+	}
+	for _, want := range []string{"nps: 11 is outside 0-10", "agree: an answer is required", "stars: expected a whole number",
+		"os: Mac is not one of the options", "apps: expected a list", "comment: text is 12 characters", "extra: not a question"} {
+		if !strings.Contains(ae.Error(), want) {
+			t.Errorf("Expected a problem mentioning %q, got:\n%s", want, strings.Join(ae.Problems, "\n"))
+		}
+	}
+
+	// A reminder or decline needs no answers, but those it carries must fit
+	if answers, err := d.Answers(map[string]interface{}{"comment": "later"}, nil); err != nil || answers["comment"] != "later" {
+		t.Errorf("A note without ratings should be accepted, got %v %v", answers, err)
+	}
+	if _, err := d.Answers(map[string]interface{}{"nps": 11, "extra": 1}, nil); err == nil || strings.Contains(err.Error(), "required") {
+		t.Errorf("Misfitting answers should be rejected without requiring any, got %v", err)
+	}
+	if answers, err := d.Answers(map[string]interface{}{model.KeyNote: "User requested reminder later"}, nil); err != nil || len(answers) != 0 {
+		t.Errorf("A note should be dropped by a survey without a note question, got %v %v", answers, err)
+	}
+	if _, err := d.Check(map[string]interface{}{"nps": 9, "agree": 4, model.KeyNote: "x"}, nil); err == nil {
+		t.Error("A completed survey should not carry answers to questions it does not ask")
+	}
+}
+
+func TestDisplayConditions(t *testing.T) {
+	d, err := Load(writeFile(t, "survey.yaml", `
+id: conditional
+version: 1
+questions:
+  - key: server_performance
+    type: rating
+    prompt: Server Experience
+    required: true
+    show_if: [{system: machine_type, equals: server}]
+  - key: technical_support
+    type: rating
+    prompt: Technical Support
+    required: true
+  - key: what_went_wrong
+    type: text
+    prompt: What went wrong?
+    required: true
+    show_if: [{answer: technical_support, equals: 1}]
+`))
+	if err != nil {
+		t.Fatalf("Failed to load definition: %v", err)
+	}
+	server := sysinfo.Attributes{sysinfo.MachineType: sysinfo.Server}
+	workstation := sysinfo.Attributes{sysinfo.MachineType: sysinfo.Workstation}
+
+	if got := strings.Join(d.Visible(nil, server), ","); got != "server_performance,technical_support" {
+		t.Errorf("Unexpected questions on a server: %s", got)
+	}
+	// JSON numbers arrive as float64
+	bad := map[string]interface{}{"technical_support": 1.0}
+	if got := strings.Join(d.Visible(bad, workstation), ","); got != "technical_support,what_went_wrong" {
+		t.Errorf("Unexpected questions after a Bad rating on a workstation: %s", got)
+	}
+
+	if _, err := d.Check(bad, workstation); err == nil || !strings.Contains(err.Error(), "what_went_wrong: an answer is required") {
+		t.Errorf("A shown follow-up question should be required, got %v", err)
+	}
+	answers, err := d.Check(map[string]interface{}{"server_performance": 3.0, "technical_support": 3.0, "what_went_wrong": "x"}, workstation)
+	if err != nil {
+		t.Fatalf("Hidden questions should not be required: %v", err)
+	}
+	if _, ok := answers["server_performance"]; ok || answers["what_went_wrong"] != nil {
+		t.Errorf("Answers to hidden questions should be dropped: %v", answers)
+	}
+
+	for name, content := range map[string]string{
+		"later question":  `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A", "show_if": [{"answer": "b", "equals": "x"}]}, {"key": "b", "type": "text", "prompt": "B"}]}`,
+		"unknown system":  `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A", "show_if": [{"system": "ram", "equals": "8"}]}]}`,
+		"two operators":   `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A", "show_if": [{"system": "os", "equals": "windows", "in": ["linux"]}]}]}`,
+		"missing subject": `{"id": "s", "version": 1, "questions": [{"key": "a", "type": "text", "prompt": "A", "show_if": [{"equals": "x"}]}]}`,
+	} {
+		if _, err := Load(writeFile(t, "survey.json", content)); err == nil || !strings.Contains(err.Error(), "show_if") {
+			t.Errorf("%s: expected a show_if error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package definition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"customer-survey/pkg/model"
+)
+
+// Question types
+const (
+	TypeRating      = "rating"       // Good/Okay/Bad, stored as 3/2/1
+	TypeNPS         = "nps"          // Net Promoter Score, 0-10
+	TypeLikert      = "likert"       // Strongly disagree (1) to Strongly agree (5)
+	TypeStars       = "stars"        // 1 to scale stars, default 5
+	TypeChoice      = "choice"       // one of options
+	TypeMultiChoice = "multi_choice" // any number of options
+	TypeText        = "text"         // free text, at most max_length characters
+)
+
+// Types lists every question type, in the order they are documented
+var Types = []string{TypeRating, TypeNPS, TypeLikert, TypeStars, TypeChoice, TypeMultiChoice, TypeText}
+
+// Limits of the configurable question settings
+const (
+	DefaultStars     = 5
+	MinStars         = 3
+	MaxStars         = 10
+	DefaultMaxLength = 200  // characters of a text answer
+	MaxMaxLength     = 2000 // upper bound for max_length
+)
+
+// RatingScales are the scales a "rating" question can have
+var RatingScales = []int{3}
+
+var likertLabels = []string{"Strongly disagree", "Disagree", "Neutral", "Agree", "Strongly agree"}
+
+// AnswerError lists every answer that does not fit its question
+type AnswerError struct {
+	Problems []string
+}
+
+func (e *AnswerError) Error() string {
+	return fmt.Sprintf("invalid answers: %s", strings.Join(e.Problems, "; "))
+}
+
+// normalize fills in the defaults of a question's type
+func (q *Question) normalize() {
+	switch q.Type {
+	case TypeRating:
+		if q.Scale == 0 {
+			q.Scale = 3
+		}
+	case TypeNPS:
+		if q.Scale == 0 {
+			q.Scale = 10
+		}
+	case TypeLikert:
+		if q.Scale == 0 {
+			q.Scale = len(likertLabels)
+		}
+	case TypeStars:
+		if q.Scale == 0 {
+			q.Scale = DefaultStars
+		}
+	case TypeText:
+		if q.MaxLength == 0 {
+			q.MaxLength = DefaultMaxLength
+		}
+	}
+}
+
+// validate checks the settings of one question for its type
+func (q *Question) validate() error {
+	if q.Type != TypeChoice && q.Type != TypeMultiChoice && len(q.Options) > 0 {
+		return fmt.Errorf("options: only %q and %q questions have options", TypeChoice, TypeMultiChoice)
+	}
+	if q.Type != TypeText && q.MaxLength != 0 {
+		return fmt.Errorf("max_length: only %q questions have a maximum length", TypeText)
+	}
+	switch q.Type {
+	case TypeRating:
+		if !KnownScale(q.Scale) {
+			return fmt.Errorf("scale: unsupported rating scale %d (supported: %v)", q.Scale, RatingScales)
+		}
+	case TypeNPS:
+		if q.Scale != 10 {
+			return fmt.Errorf("scale: an NPS question is always 0-10, got %d", q.Scale)
+		}
+	case TypeLikert:
+		if q.Scale != len(likertLabels) {
+			return fmt.Errorf("scale: a Likert question has %d points, got %d", len(likertLabels), q.Scale)
+		}
+	case TypeStars:
+		if q.Scale < MinStars || q.Scale > MaxStars {
+			return fmt.Errorf("scale: stars must be between %d and %d, got %d", MinStars, MaxStars, q.Scale)
+		}
+	case TypeChoice, TypeMultiChoice:
+		if len(q.Options) < 2 {
+			return fmt.Errorf("options: need at least 2, got %d", len(q.Options))
+		}
+		seen := map[string]bool{}
+		for i, o := range q.Options {
+			if strings.TrimSpace(o) == "" {
+				return fmt.Errorf("options[%d]: is empty", i)
+			}
+			if seen[o] {
+				return fmt.Errorf("options[%d]: %q is listed twice", i, o)
+			}
+			seen[o] = true
+		}
+	case TypeText:
+		if q.MaxLength < 1 || q.MaxLength > MaxMaxLength {
+			return fmt.Errorf("max_length: must be between 1 and %d, got %d", MaxMaxLength, q.MaxLength)
+		}
+	default:
+		return fmt.Errorf("type: unknown type %q (expected one of %s)", q.Type, strings.Join(Types, ", "))
+	}
+	return nil
+}
+
+// Range returns the lowest and highest answer of a numeric question; ok is
+// false for choice and text questions
+func (q Question) Range() (min, max int, ok bool) {
+	switch q.Type {
+	case TypeRating, TypeLikert, TypeStars:
+		return 1, q.Scale, true
+	case TypeNPS:
+		return 0, q.Scale, true
+	}
+	return 0, 0, false
+}
+
+// Check converts an answer to the form it is stored in (int, string or
+// []string) and reports answers that do not fit the question. nil, "" and
+// an empty list count as unanswered and return nil without an error.
+func (q Question) Check(v interface{}) (interface{}, error) {
+	if empty(v) {
+		return nil, nil
+	}
+	if min, max, ok := q.Range(); ok {
+		n, isInt := model.Answers{q.Key: v}.Int(q.Key)
+		if !isInt {
+			return nil, fmt.Errorf("expected a whole number, got %v", v)
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("%d is outside %d-%d", n, min, max)
+		}
+		return n, nil
+	}
+
+	switch q.Type {
+	case TypeChoice:
+		s, ok := v.(string)
+		if !ok || !q.hasOption(s) {
+			return nil, fmt.Errorf("%v is not one of the options", v)
+		}
+		return s, nil
+	case TypeMultiChoice:
+		var picked []string
+		seen := map[string]bool{}
+		items, ok := v.([]interface{})
+		if strs, isStrings := v.([]string); isStrings {
+			for _, s := range strs {
+				items = append(items, s)
+			}
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("expected a list of options, got %v", v)
+		}
+		for _, item := range items {
+			s, isString := item.(string)
+			if !isString || !q.hasOption(s) {
+				return nil, fmt.Errorf("%v is not one of the options", item)
+			}
+			if !seen[s] {
+				seen[s] = true
+				picked = append(picked, s)
+			}
+		}
+		return picked, nil
+	case TypeText:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected text, got %v", v)
+		}
+		s = strings.TrimSpace(s)
+		if n := len([]rune(s)); n > q.MaxLength {
+			return nil, fmt.Errorf("text is %d characters, at most %d are allowed", n, q.MaxLength)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown question type %q", q.Type)
+}
+
+// Label returns the text a stored answer stands for, e.g. "Good" or
+// "Promoter"; choice answers are their own label and text has none
+func (q Question) Label(v interface{}) string {
+	n, isInt := model.Answers{q.Key: v}.Int(q.Key)
+	switch q.Type {
+	case TypeRating:
+		switch n {
+		case 3:
+			return "Good"
+		case 2:
+			return "Okay"
+		case 1:
+			return "Bad"
+		}
+	case TypeNPS:
+		switch {
+		case !isInt:
+		case n >= 9:
+			return "Promoter"
+		case n >= 7:
+			return "Passive"
+		default:
+			return "Detractor"
+		}
+	case TypeLikert:
+		if isInt && n >= 1 && n <= len(likertLabels) {
+			return likertLabels[n-1]
+		}
+	case TypeStars:
+		if isInt {
+			return strconv.Itoa(n) + "/" + strconv.Itoa(q.Scale)
+		}
+	case TypeChoice, TypeMultiChoice:
+		return model.Text(v)
+	}
+	return ""
+}
+
+func (q Question) hasOption(s string) bool {
+	for _, o := range q.Options {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
+
+// empty reports an unanswered question
+func empty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
@@ -1,11 +1,182 @@
 package model
 
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type SurveyResponse struct {
-	ServerName        string `json:"server_name"`
-	UserName          string `json:"user_name"`
-	SurveyResponse    string `json:"survey_response"` // "completed" or "declined"
-	ServerPerformance int    `json:"server_performance"`
-	TechnicalSupport  int    `json:"technical_support"`
-	OverallSupport    int    `json:"overall_support"`
-	Note              string `json:"note,omitempty"`
+	SubmissionID   string            `json:"submission_id"` // stable UUID, also sent as the Idempotency-Key header
+	AnsweredAt     time.Time         `json:"answered_at"`   // when the user answered, not when it was sent
+	SurveyID       string            `json:"survey_id,omitempty"`
+	SurveyVersion  int               `json:"survey_version,omitempty"`
+	Campaign       string            `json:"campaign,omitempty"` // campaign id, empty outside campaigns
+	ServerName     string            `json:"server_name"`
+	UserName       string            `json:"user_name"`
+	SurveyResponse string            `json:"survey_response"` // one of the Response or Desktop constants
+	Answers        Answers           `json:"answers,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"` // e.g. "Good" or "Promoter", by question key
+
+	// Set on "Remind Me Later" events
+	Snoozes  int        `json:"snoozes,omitempty"`   // Remind Me Later in a row, including this one
+	RemindAt *time.Time `json:"remind_at,omitempty"` // when the survey is shown again, nil if it is not
+}
+
+// Values of SurveyResponse sent by the browser and message box UIs
+const (
+	ResponseCompleted   = "completed"    // the survey was answered
+	ResponseDeclined    = "declined"     // No Thanks
+	ResponseRemindLater = "remind_later" // Remind Me Later
+)
+
+// Values of SurveyResponse sent by the desktop (Wails) app. Zoho Flows and
+// sheets set up for it filter on them, so they stay as they always were.
+const (
+	DesktopCompleted   = "Complete"
+	DesktopDeclined    = "No Thanks"
+	DesktopRemindLater = "Remind Me Later"
+)
+
+// Answers maps question keys from the survey definition to the answers given
+type Answers map[string]interface{}
+
+// Keys of the questions every survey has asked so far. Records written
+// before answers were keyed carried them as top-level fields.
+const (
+	KeyServerPerformance = "server_performance"
+	KeyTechnicalSupport  = "technical_support"
+	KeyOverallSupport    = "overall_support"
+	KeyNote              = "note"
+)
+
+// Int returns a numeric answer; ok is false if it is missing or not a number
+func (a Answers) Int(key string) (int, bool) {
+	switch v := a[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), v == float64(int(v))
+	case json.Number:
+		n, err := strconv.Atoi(string(v))
+		return n, err == nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// String returns an answer as text; lists are joined with ", "
+func (a Answers) String(key string) string {
+	return Text(a[key])
+}
+
+// Text formats an answer value as text; lists are joined with ", "
+func Text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// UnmarshalJSON also accepts records written before answers were keyed
+// and moves their rating and note fields into Answers
+func (r *SurveyResponse) UnmarshalJSON(data []byte) error {
+	type plain SurveyResponse
+	var legacy struct {
+		plain
+		ServerPerformance *int    `json:"server_performance"`
+		TechnicalSupport  *int    `json:"technical_support"`
+		OverallSupport    *int    `json:"overall_support"`
+		Note              *string `json:"note"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	*r = SurveyResponse(legacy.plain)
+
+	set := func(key string, v interface{}) {
+		if r.Answers == nil {
+			r.Answers = Answers{}
+		}
+		if _, ok := r.Answers[key]; !ok {
+			r.Answers[key] = v
+		}
+	}
+	if legacy.ServerPerformance != nil && *legacy.ServerPerformance != 0 {
+		set(KeyServerPerformance, *legacy.ServerPerformance)
+	}
+	if legacy.TechnicalSupport != nil && *legacy.TechnicalSupport != 0 {
+		set(KeyTechnicalSupport, *legacy.TechnicalSupport)
+	}
+	if legacy.OverallSupport != nil && *legacy.OverallSupport != 0 {
+		set(KeyOverallSupport, *legacy.OverallSupport)
+	}
+	if legacy.Note != nil && *legacy.Note != "" {
+		set(KeyNote, *legacy.Note)
+	}
+	return nil
+}
+
+// NewSubmissionID returns a random (version 4) UUID identifying one answer
+func NewSubmissionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms; keep IDs unique regardless
+		n := time.Now().UnixNano()
+		for i := range b {
+			b[i] = byte(n >> (8 * (i % 8)))
+		}
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ValidSubmissionID reports whether id has the form of NewSubmissionID, as
+// a UI that creates the ID itself must send it
+func ValidSubmissionID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, c := range id {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if c != '-' {
+				return false
+			}
+		case !strings.ContainsRune("0123456789abcdefABCDEF", c):
+			return false
+		}
+	}
+	return true
+}
+
+// Stamp fills in the submission ID and answer time if they are not set yet.
+// Call it once, at the moment the user answers.
+func (r *SurveyResponse) Stamp() {
+	if r.SubmissionID == "" {
+		r.SubmissionID = NewSubmissionID()
+	}
+	if r.AnsweredAt.IsZero() {
+		r.AnsweredAt = time.Now()
+	}
 }
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLegacyRecordFieldsMoveIntoAnswers(t *testing.T) {
+	var r SurveyResponse
+	data := `{"submission_id": "id-1", "survey_response": "completed", "server_performance": 3, "technical_support": 2, "overall_support": 0, "note": "fast"}`
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatalf("Failed to read legacy record: %v", err)
+	}
+	if n, ok := r.Answers.Int(KeyServerPerformance); !ok || n != 3 {
+		t.Errorf("Expected server_performance 3, got %v", r.Answers)
+	}
+	if _, ok := r.Answers[KeyOverallSupport]; ok {
+		t.Errorf("Unanswered ratings should not become answers: %v", r.Answers)
+	}
+	if r.Answers.String(KeyNote) != "fast" || r.SubmissionID != "id-1" {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+
+	// Round trip through the current format keeps the answers
+	out, _ := json.Marshal(r)
+	var again SurveyResponse
+	if err := json.Unmarshal(out, &again); err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if n, ok := again.Answers.Int(KeyTechnicalSupport); !ok || n != 2 {
+		t.Errorf("Answers lost in round trip: %s", out)
+	}
+}
+
+func TestSubmissionIDs(t *testing.T) {
+	id := NewSubmissionID()
+	if !ValidSubmissionID(id) || id == NewSubmissionID() {
+		t.Errorf("Expected a new valid UUID, got %q", id)
+	}
+	for _, bad := range []string{"", "id-1", id + "0", "0a1b2c3d-4e5f-4a7b-8c9d-0e1f2a3b4c5g", "0a1b2c3d-4e5f-4a7b-8c9d_0e1f2a3b4c5d"} {
+		if ValidSubmissionID(bad) {
+			t.Errorf("%q should not be a valid submission id", bad)
+		}
+	}
+
+	// An ID given at answer time is kept
+	r := SurveyResponse{SubmissionID: id}
+	r.Stamp()
+	if r.SubmissionID != id || r.AnsweredAt.IsZero() {
+		t.Errorf("Stamp should keep the id and set the time, got %+v", r)
+	}
+}
@@ -0,0 +1,268 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"customer-survey/pkg/appdata"
+	"customer-survey/pkg/model"
+)
+
+// FileName is the name of the outbox file inside the per-user AppData folder
+const FileName = "outbox.jsonl"
+
+// Status is the delivery state of a queued submission
+type Status string
+
+const (
+	StatusPending Status = "pending" // not delivered yet, will be retried
+	StatusSent    Status = "sent"    // accepted by the destination
+	StatusFailed  Status = "failed"  // rejected permanently, kept for inspection
+)
+
+// Record is one survey submission together with its delivery state
+type Record struct {
+	ID        string               `json:"id"`
+	Status    Status               `json:"status"`
+	Attempts  int                  `json:"attempts"`
+	LastError string               `json:"last_error,omitempty"`
+	Delivered []string             `json:"delivered,omitempty"` // destinations that accepted the response and are not sent it again
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Response  model.SurveyResponse `json:"response"`
+}
+
+// Outbox is an append-only, line-delimited JSON log of submissions.
+// Every state change appends a new copy of the record; when reading,
+// the last line for a given ID wins. Nothing is ever overwritten in place,
+// so a failed submission can never wipe an earlier unsent one.
+type Outbox struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New returns an outbox stored at path
+func New(path string) *Outbox {
+	return &Outbox{path: path}
+}
+
+// Default returns the per-user outbox in %APPDATA%\CustomerSurvey
+func Default() *Outbox {
+	return New(filepath.Join(appdata.Dir(), FileName))
+}
+
+// Path returns the file backing the outbox
+func (o *Outbox) Path() string {
+	return o.path
+}
+
+// Add queues a new submission as pending and returns the stored record.
+// The record ID is the response's submission ID, so adding the same answer
+// twice returns the existing record instead of queueing a duplicate.
+func (o *Outbox) Add(resp model.SurveyResponse) (*Record, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	resp.Stamp()
+
+	records, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == resp.SubmissionID {
+			return &records[i], nil
+		}
+	}
+
+	now := time.Now()
+	rec := &Record{
+		ID:        resp.SubmissionID,
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Response:  resp,
+	}
+	if err := o.appendRecord(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// List returns the current state of every record, oldest first
+func (o *Outbox) List() ([]Record, error) {
+	unlock, err := o.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return o.load()
+}
+
+// Pending returns the records that still need to be delivered, oldest first
+func (o *Outbox) Pending() ([]Record, error) {
+	records, err := o.List()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Record
+	for _, rec := range records {
+		if rec.Status == StatusPending {
+			pending = append(pending, rec)
+		}
+	}
+	return pending, nil
+}
+
+// Compact rewrites the file so it holds exactly one line per record.
+// The new file is written next to the old one and renamed over it.
+func (o *Outbox) Compact() error {
+	unlock, err := o.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := o.load()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i := range records {
+		line, err := json.Marshal(&records[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := appdata.WriteFileAtomic(o.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to replace outbox: %w", err)
+	}
+	return nil
+}
+
+// lock serializes access to the file, within the process and with other
+// sessions of the same user
+func (o *Outbox) lock() (unlock func(), err error) {
+	o.mu.Lock()
+	unlockFile, err := appdata.LockFile(o.path + ".lock")
+	if err != nil {
+		o.mu.Unlock()
+		return nil, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	return func() {
+		unlockFile()
+		o.mu.Unlock()
+	}, nil
+}
+
+// update applies fn to the latest copy of a record and appends the result
+func (o *Outbox) update(id string, fn func(*Record)) error {
+	unlock, err := o.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	records, err := o.load()
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			rec := records[i]
+			fn(&rec)
+			rec.UpdatedAt = time.Now()
+			return o.appendRecord(&rec)
+		}
+	}
+	return fmt.Errorf("outbox record %s not found", id)
+}
+
+// appendRecord writes a single record as one JSON line
+func (o *Outbox) appendRecord(rec *Record) error {
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox record: %w", err)
+	}
+
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	return f.Sync()
+}
+
+// load reads the file and folds it into the latest state per record.
+// Lines that cannot be parsed (e.g. a write cut short by a crash) are skipped.
+func (o *Outbox) load() ([]Record, error) {
+	f, err := os.Open(o.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	index := map[string]int{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil || rec.ID == "" {
+			continue
+		}
+		// Records queued before submission IDs existed keep their record ID
+		if rec.Response.SubmissionID == "" {
+			rec.Response.SubmissionID = rec.ID
+		}
+		if rec.Response.AnsweredAt.IsZero() {
+			rec.Response.AnsweredAt = rec.CreatedAt
+		}
+		if i, ok := index[rec.ID]; ok {
+			records[i] = rec
+		} else {
+			index[rec.ID] = len(records)
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return records, nil
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
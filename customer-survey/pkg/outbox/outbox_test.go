@@ -0,0 +1,234 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+)
+
+func TestAddKeepsEveryRecord(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), FileName))
+
+	first, err := box.Add(model.SurveyResponse{UserName: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to add first record: %v", err)
+	}
+	second, err := box.Add(model.SurveyResponse{UserName: "bob"})
+	if err != nil {
+		t.Fatalf("Failed to add second record: %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatal("Records should get distinct IDs")
+	}
+	if first.ID != first.Response.SubmissionID || first.Response.AnsweredAt.IsZero() {
+		t.Errorf("Record should be keyed by a stamped submission ID: %+v", first)
+	}
+
+	// Queueing the same answer again must not create a second record
+	again, err := box.Add(first.Response)
+	if err != nil {
+		t.Fatalf("Failed to re-add record: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("Re-adding should return the existing record, got %s", again.ID)
+	}
+
+	pending, err := box.Pending()
+	if err != nil {
+		t.Fatalf("Failed to list pending records: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending records, got %d", len(pending))
+	}
+	if pending[0].Response.UserName != "alice" || pending[1].Response.UserName != "bob" {
+		t.Errorf("Pending records out of order: %+v", pending)
+	}
+}
+
+// sendResult returns a SendFunc that accepts every response, or fails it with err
+func sendResult(err error) SendFunc {
+	return func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+		if err != nil {
+			return delivered, err
+		}
+		return []string{"webhook"}, nil
+	}
+}
+
+// stored returns the record with the ID as it is on disk
+func stored(t *testing.T, box *Outbox, id string) Record {
+	t.Helper()
+	records, err := box.List()
+	if err != nil {
+		t.Fatalf("Failed to list records: %v", err)
+	}
+	for _, rec := range records {
+		if rec.ID == id {
+			return rec
+		}
+	}
+	t.Fatalf("Record %s not found", id)
+	return Record{}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), FileName))
+	ctx := context.Background()
+
+	rec, err := box.Add(model.SurveyResponse{UserName: "alice"})
+	if err != nil {
+		t.Fatalf("Failed to add record: %v", err)
+	}
+
+	if _, err := box.Deliver(ctx, rec, sendResult(errors.New("connection refused"))); err != nil {
+		t.Fatalf("Failed to record attempt: %v", err)
+	}
+	got := stored(t, box, rec.ID)
+	if got.Status != StatusPending || got.Attempts != 1 || got.LastError != "connection refused" {
+		t.Errorf("Unexpected state after failed attempt: %+v", got)
+	}
+
+	if _, err := box.Deliver(ctx, rec, sendResult(nil)); err != nil {
+		t.Fatalf("Failed to record delivery: %v", err)
+	}
+	got = stored(t, box, rec.ID)
+	if got.Status != StatusSent || got.Attempts != 2 || got.LastError != "" {
+		t.Errorf("Unexpected state after delivery: %+v", got)
+	}
+
+	pending, _ := box.Pending()
+	if len(pending) != 0 {
+		t.Errorf("Sent record should not be pending, got %d", len(pending))
+	}
+
+	other, _ := box.Add(model.SurveyResponse{UserName: "bob"})
+	if _, err := box.Deliver(ctx, other, sendResult(retry.Permanent(errors.New("HTTP 400")))); err != nil {
+		t.Fatalf("Failed to record failure: %v", err)
+	}
+	if got := stored(t, box, other.ID); got.Status != StatusFailed {
+		t.Errorf("Expected failed status, got %s", got.Status)
+	}
+}
+
+func TestCompactAndTruncatedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	box := New(path)
+
+	rec, _ := box.Add(model.SurveyResponse{UserName: "alice"})
+	box.Deliver(context.Background(), rec, sendResult(errors.New("timeout")))
+	box.Deliver(context.Background(), rec, sendResult(errors.New("timeout")))
+
+	// Simulate a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open outbox: %v", err)
+	}
+	f.WriteString(`{"id":"broken","sta`)
+	f.Close()
+
+	if err := box.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+
+	records, err := box.List()
+	if err != nil {
+		t.Fatalf("Failed to list records: %v", err)
+	}
+	if len(records) != 1 || records[0].Attempts != 2 {
+		t.Fatalf("Unexpected records after compaction: %+v", records)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := 0
+	for _, b := range data {
+		if b == '\n' {
+			lines++
+		}
+	}
+	if lines != 1 {
+		t.Errorf("Compacted file should have 1 line, got %d", lines)
+	}
+}
+
+func TestReplayMarksOnlyAcceptedRecords(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), FileName))
+
+	ok, _ := box.Add(model.SurveyResponse{UserName: "alice"})
+	bad, _ := box.Add(model.SurveyResponse{UserName: "bob"})
+	done, _ := box.Add(model.SurveyResponse{UserName: "carol"})
+	box.Deliver(context.Background(), done, sendResult(nil))
+	rejected, _ := box.Add(model.SurveyResponse{UserName: "dave"})
+
+	var sent []string
+	result, err := box.Replay(context.Background(), func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+		sent = append(sent, resp.UserName)
+		switch resp.UserName {
+		case "bob":
+			return nil, errors.New("HTTP 503")
+		case "dave":
+			return nil, retry.Permanent(errors.New("HTTP 400"))
+		}
+		return []string{"webhook"}, nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if result.Sent != 1 || result.Pending != 1 || result.Failed != 1 {
+		t.Errorf("Unexpected replay result: %+v", result)
+	}
+	if len(sent) != 3 {
+		t.Errorf("Already delivered records should not be resent, sent: %v", sent)
+	}
+
+	got := stored(t, box, ok.ID)
+	if got.Status != StatusSent {
+		t.Errorf("Accepted record should be sent, got %s", got.Status)
+	}
+	got = stored(t, box, bad.ID)
+	if got.Status != StatusPending || got.LastError != "HTTP 503" {
+		t.Errorf("Rejected record should stay pending with error, got %+v", got)
+	}
+	got = stored(t, box, rejected.ID)
+	if got.Status != StatusFailed {
+		t.Errorf("Permanently rejected record should be failed, got %s", got.Status)
+	}
+}
+
+func TestDeliverRetriesOnlyFailedDestinations(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), FileName))
+	rec, _ := box.Add(model.SurveyResponse{UserName: "alice"})
+
+	// The file accepts the response, the webhook is down
+	var attempts [][]string
+	send := func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+		attempts = append(attempts, delivered)
+		if len(attempts) == 1 {
+			return []string{"file"}, errors.New("webhook: HTTP 503")
+		}
+		return append(delivered, "webhook"), nil
+	}
+	if sendErr, err := box.Deliver(context.Background(), rec, send); sendErr == nil || err != nil {
+		t.Fatalf("Expected the send to fail and the outcome to be stored: %v, %v", sendErr, err)
+	}
+	if rec.Status != StatusPending || len(rec.Delivered) != 1 {
+		t.Errorf("Record should stay pending with the file delivered, got %+v", rec)
+	}
+
+	// The next launch replays it to the webhook only
+	result, err := box.Replay(context.Background(), send)
+	if err != nil || result.Sent != 1 {
+		t.Fatalf("Replay failed: %+v, %v", result, err)
+	}
+	if len(attempts) != 2 || len(attempts[1]) != 1 || attempts[1][0] != "file" {
+		t.Errorf("The replay should skip the file, got %v", attempts)
+	}
+	got := stored(t, box, rec.ID)
+	if got.Status != StatusSent || len(got.Delivered) != 2 || got.Attempts != 2 {
+		t.Errorf("Unexpected record after replay: %+v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+)
+
+// SendFunc delivers a single response, skipping the destinations named in
+// delivered, and returns the names of every destination that has accepted
+// it by now, also when it fails (see sink.Deliver). It must return a nil
+// error only when every required destination accepted the response (a 2xx
+// for HTTP destinations).
+type SendFunc func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error)
+
+// ReplayResult summarises one pass over the pending records
+type ReplayResult struct {
+	Sent    int // accepted, marked sent
+	Pending int // failed transiently, still pending for the next launch
+	Failed  int // rejected permanently, marked failed
+}
+
+// Replayed is the number of records sent in the pass
+func (r ReplayResult) Replayed() int {
+	return r.Sent + r.Pending + r.Failed
+}
+
+func (r ReplayResult) String() string {
+	return fmt.Sprintf("%d sent, %d still pending, %d failed permanently", r.Sent, r.Pending, r.Failed)
+}
+
+// Deliver sends rec through send, skipping the destinations that accepted
+// it on an earlier attempt, and stores the outcome in rec and the outbox:
+// sent once send succeeds, failed when it fails permanently (see
+// retry.IsPermanent), pending otherwise. sendErr is the error of send, err
+// the error of storing the outcome.
+func (o *Outbox) Deliver(ctx context.Context, rec *Record, send SendFunc) (sendErr, err error) {
+	delivered, sendErr := send(ctx, rec.Response, rec.Delivered)
+	err = o.update(rec.ID, func(stored *Record) {
+		stored.Attempts++
+		stored.Delivered = delivered
+		stored.LastError = errorText(sendErr)
+		switch {
+		case sendErr == nil:
+			stored.Status = StatusSent
+		case retry.IsPermanent(sendErr):
+			stored.Status = StatusFailed
+		default:
+			stored.Status = StatusPending
+		}
+		*rec = *stored
+	})
+	return sendErr, err
+}
+
+// Replay re-sends every pending record, oldest first. A record is marked sent
+// only when send returns nil; transient failures are recorded and the record
+// stays pending for the next launch, while permanent failures (see
+// retry.IsPermanent) are marked failed and not retried again.
+// Replay stops early if ctx is cancelled.
+func (o *Outbox) Replay(ctx context.Context, send SendFunc) (ReplayResult, error) {
+	var result ReplayResult
+
+	pending, err := o.Pending()
+	if err != nil {
+		return result, err
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+	log.Printf("[outbox] Replaying %d pending submission(s) from %s", len(pending), o.path)
+
+	for _, rec := range pending {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		sendErr, err := o.Deliver(ctx, &rec, send)
+		switch {
+		case sendErr == nil:
+			result.Sent++
+			log.Printf("[outbox] Replayed %s", rec.ID)
+		case retry.IsPermanent(sendErr):
+			result.Failed++
+			log.Printf("[outbox] Replay of %s failed permanently (attempt %d), marked failed: %v", rec.ID, rec.Attempts, sendErr)
+		default:
+			result.Pending++
+			log.Printf("[outbox] Replay of %s failed (attempt %d), still pending: %v", rec.ID, rec.Attempts, sendErr)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Keep the file from growing with every state change
+	if err := o.Compact(); err != nil {
+		log.Printf("[outbox] Could not compact outbox: %v", err)
+	}
+	return result, nil
+}
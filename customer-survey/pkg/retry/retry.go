@@ -0,0 +1,222 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy controls how often and how quickly a failed delivery is retried
+type Policy struct {
+	MaxAttempts int           // total attempts, including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled on each retry
+	MaxDelay    time.Duration // upper bound for a single delay
+	Jitter      float64       // fraction (0-1) of each delay that is randomised
+}
+
+// DefaultPolicy is used when config.json has no "retry" section
+var DefaultPolicy = Policy{
+	MaxAttempts: 4,
+	BaseDelay:   1 * time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.5,
+}
+
+// Config is the config.json form of a Policy. Zero values fall back to DefaultPolicy.
+type Config struct {
+	MaxAttempts int      `json:"max_attempts"`
+	BaseDelayMS int      `json:"base_delay_ms"`
+	MaxDelayMS  int      `json:"max_delay_ms"`
+	Jitter      *float64 `json:"jitter"`
+}
+
+// Policy converts the config section into a Policy
+func (c *Config) Policy() Policy {
+	p := DefaultPolicy
+	if c == nil {
+		return p
+	}
+	if c.MaxAttempts > 0 {
+		p.MaxAttempts = c.MaxAttempts
+	}
+	if c.BaseDelayMS > 0 {
+		p.BaseDelay = time.Duration(c.BaseDelayMS) * time.Millisecond
+	}
+	if c.MaxDelayMS > 0 {
+		p.MaxDelay = time.Duration(c.MaxDelayMS) * time.Millisecond
+	}
+	if c.Jitter != nil && *c.Jitter >= 0 && *c.Jitter <= 1 {
+		p.Jitter = *c.Jitter
+	}
+	return p
+}
+
+// Backoff returns the delay before retry number n (n starts at 1)
+func (p Policy) Backoff(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(p.Jitter * rand.Float64() * float64(d))
+	}
+	return d
+}
+
+// StatusError is returned for a non-2xx HTTP response
+type StatusError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // parsed Retry-After header, zero if absent
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the status is worth retrying: 408, 429 and 5xx
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// CheckResponse returns nil for a 2xx response and a *StatusError otherwise
+func CheckResponse(res *http.Response, body []byte) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{
+		StatusCode: res.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds and HTTP-date
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type stopError struct {
+	err error
+}
+
+func (e *stopError) Error() string { return e.err.Error() }
+func (e *stopError) Unwrap() error { return e.err }
+
+// Stop ends the current retry loop without marking err as permanent: the
+// submission itself is fine, but retrying now cannot help (e.g. revoked
+// credentials). It stays queued and is tried again on a later launch.
+func Stop(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &stopError{err: err}
+}
+
+// IsStopped reports whether err was wrapped with Stop
+func IsStopped(err error) bool {
+	var se *stopError
+	return errors.As(err, &se)
+}
+
+// IsPermanent reports whether retrying err can never succeed: errors wrapped
+// with Permanent and HTTP statuses other than 408, 429 and 5xx.
+// Network errors and timeouts are not permanent.
+func IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pe *permanentError
+	if errors.As(err, &pe) {
+		return true
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return !se.Retryable()
+	}
+	return false
+}
+
+// Do calls fn until it succeeds, returns a permanent or stopped error, runs
+// out of attempts or ctx is done. A Retry-After from the server replaces the computed
+// backoff; if it is longer than MaxDelay Do gives up and returns the error so
+// the caller can keep the submission queued for later.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context, attempt int) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn(ctx, attempt)
+		if err == nil || IsPermanent(err) || IsStopped(err) || attempt == attempts {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		delay := p.Backoff(attempt)
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > 0 {
+			if p.MaxDelay > 0 && se.RetryAfter > p.MaxDelay {
+				return err
+			}
+			delay = se.RetryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
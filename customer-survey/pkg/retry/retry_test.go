@@ -0,0 +1,140 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+var fastPolicy = Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fastPolicy, func(ctx context.Context, attempt int) error {
+		calls++
+		if attempt < 3 {
+			return &StatusError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success on third attempt, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDoStopsOnPermanentErrors(t *testing.T) {
+	for _, fail := range []error{
+		&StatusError{StatusCode: http.StatusBadRequest},
+		Permanent(errors.New("bad config")),
+	} {
+		calls := 0
+		err := Do(context.Background(), fastPolicy, func(ctx context.Context, attempt int) error {
+			calls++
+			return fail
+		})
+		if !IsPermanent(err) {
+			t.Errorf("Expected permanent error, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Permanent error %v should not be retried, got %d calls", fail, calls)
+		}
+	}
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fastPolicy, func(ctx context.Context, attempt int) error {
+		calls++
+		return errors.New("connection reset")
+	})
+	if err == nil || IsPermanent(err) {
+		t.Errorf("Expected transient error after exhausting attempts, got %v", err)
+	}
+	if calls != fastPolicy.MaxAttempts {
+		t.Errorf("Expected %d calls, got %d", fastPolicy.MaxAttempts, calls)
+	}
+}
+
+func TestDoHonoursLongRetryAfter(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fastPolicy, func(ctx context.Context, attempt int) error {
+		calls++
+		return &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}
+	})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if calls != 1 {
+		t.Errorf("Retry-After beyond MaxDelay should stop retrying, got %d calls", calls)
+	}
+}
+
+func TestStatusClassification(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusNotFound:            false,
+	}
+	for code, retryable := range cases {
+		if got := (&StatusError{StatusCode: code}).Retryable(); got != retryable {
+			t.Errorf("HTTP %d: expected retryable=%v, got %v", code, retryable, got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("5", now); d != 5*time.Second {
+		t.Errorf("Expected 5s, got %v", d)
+	}
+	date := now.Add(90 * time.Second).Format(http.TimeFormat)
+	if d := parseRetryAfter(date, now); d != 90*time.Second {
+		t.Errorf("Expected 90s, got %v", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Errorf("Expected 0 for invalid header, got %v", d)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	if d := p.Backoff(1); d != time.Second {
+		t.Errorf("Expected 1s, got %v", d)
+	}
+	if d := p.Backoff(3); d != 4*time.Second {
+		t.Errorf("Expected 4s, got %v", d)
+	}
+	if d := p.Backoff(10); d != 5*time.Second {
+		t.Errorf("Expected cap of 5s, got %v", d)
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if d := p.Backoff(1); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("Jittered delay out of range: %v", d)
+		}
+	}
+}
+
+func TestDoStopsWithoutMarkingPermanent(t *testing.T) {
+	calls := 0
+	err := Do(context.Background(), fastPolicy, func(ctx context.Context, attempt int) error {
+		calls++
+		return Stop(errors.New("refresh token revoked"))
+	})
+	if calls != 1 {
+		t.Errorf("Stopped error should not be retried, got %d calls", calls)
+	}
+	if IsPermanent(err) || !IsStopped(err) {
+		t.Errorf("Stopped error should stay non-permanent, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/zoho"
+)
+
+// Creator submits responses to a Zoho Creator form using OAuth
+type Creator struct {
+	name   string
+	auth   *zoho.Auth
+	fields Mapping
+	policy retry.Policy
+}
+
+// NewCreator creates a Zoho Creator sink sending CreatorFields
+func NewCreator(name string, auth *zoho.Auth, policy retry.Policy) *Creator {
+	return &Creator{name: name, auth: auth, fields: CreatorFields, policy: policy}
+}
+
+func (c *Creator) Name() string { return c.name }
+
+// SetFields replaces the default form field mapping
+func (c *Creator) SetFields(m Mapping) {
+	c.fields = m
+}
+
+// Send adds one record to the Creator form
+func (c *Creator) Send(ctx context.Context, resp model.SurveyResponse) error {
+	header := http.Header{}
+	header.Set("Idempotency-Key", resp.SubmissionID)
+
+	return retry.Do(ctx, c.policy, func(ctx context.Context, attempt int) error {
+		err := c.auth.SubmitToZohoCreator(ctx, c.fields.Apply(resp, time.Now()), header)
+		if err != nil {
+			log.Printf("[%s] Attempt %d/%d to %s failed: %v", c.name, attempt, c.policy.MaxAttempts, c.auth.GetAPIEndpoint(), err)
+			return err
+		}
+		log.Printf("[%s] Delivered %s", c.name, resp.SubmissionID)
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+)
+
+// Target is a sink inside a FanOut together with its failure semantics
+type Target struct {
+	Sink     Sink
+	Required bool // a failing required sink fails the submission
+}
+
+// FanOut sends every response to several sinks in parallel, e.g. to
+// dual-write during a migration. Send fails only if a required sink fails;
+// failures of optional sinks are logged and otherwise ignored.
+//
+// Send goes to every sink. A resend after a failure should go through
+// Deliver, which skips the sinks that already accepted the response.
+type FanOut struct {
+	targets []Target
+}
+
+// NewFanOut creates a FanOut over the given targets
+func NewFanOut(targets ...Target) *FanOut {
+	return &FanOut{targets: targets}
+}
+
+// Name lists the sinks the response is fanned out to
+func (f *FanOut) Name() string {
+	names := make([]string, len(f.targets))
+	for i, t := range f.targets {
+		names[i] = t.Sink.Name()
+	}
+	return "fanout(" + strings.Join(names, ",") + ")"
+}
+
+// Send delivers resp to every target and combines the required failures
+func (f *FanOut) Send(ctx context.Context, resp model.SurveyResponse) error {
+	_, err := f.deliver(ctx, resp, nil)
+	return err
+}
+
+// deliver sends resp to the targets not named in delivered and returns the
+// names of every target that has accepted it by now
+func (f *FanOut) deliver(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+	errs := make([]error, len(f.targets))
+	accepted := append([]string(nil), delivered...)
+
+	var wg sync.WaitGroup
+	for i, t := range f.targets {
+		if contains(delivered, t.Sink.Name()) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			errs[i] = t.Sink.Send(ctx, resp)
+		}(i, t)
+	}
+	wg.Wait()
+
+	var failed []error
+	permanent := true
+	for i, t := range f.targets {
+		err := errs[i]
+		if err == nil {
+			if !contains(accepted, t.Sink.Name()) {
+				accepted = append(accepted, t.Sink.Name())
+			}
+			continue
+		}
+		if !t.Required {
+			log.Printf("[%s] Optional sink failed, ignoring: %v", t.Sink.Name(), err)
+			continue
+		}
+		failed = append(failed, fmt.Errorf("%s: %w", t.Sink.Name(), err))
+		if !retry.IsPermanent(err) {
+			permanent = false
+		}
+	}
+
+	if len(failed) == 0 {
+		return accepted, nil
+	}
+	err := errors.Join(failed...)
+	if permanent {
+		return accepted, retry.Permanent(err)
+	}
+	return accepted, err
+}
+
+// Deliver sends resp to s, skipping the sinks named in delivered, which
+// accepted it on an earlier attempt. It returns the names of every sink
+// that has accepted resp by now, also when it fails, so the next attempt
+// only goes to the sinks that did not.
+func Deliver(ctx context.Context, s Sink, resp model.SurveyResponse, delivered []string) ([]string, error) {
+	if f, ok := s.(*FanOut); ok {
+		return f.deliver(ctx, resp, delivered)
+	}
+	if contains(delivered, s.Name()) {
+		return delivered, nil
+	}
+	if err := s.Send(ctx, resp); err != nil {
+		return delivered, err
+	}
+	return append(append([]string(nil), delivered...), s.Name()), nil
+}
+
+// Sender returns Deliver bound to s, in the form the outbox sends with
+func Sender(s Sink) func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+	return func(ctx context.Context, resp model.SurveyResponse, delivered []string) ([]string, error) {
+		return Deliver(ctx, s, resp, delivered)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
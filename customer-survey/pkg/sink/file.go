@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+)
+
+// File appends every response as one JSON line to a local file
+type File struct {
+	name   string
+	path   string
+	fields Mapping // nil writes the whole response
+	mu     sync.Mutex
+}
+
+// NewFile creates a file sink writing to path
+func NewFile(name, path string) *File {
+	return &File{name: name, path: expandPath(path)}
+}
+
+func (f *File) Name() string { return f.name }
+
+// SetFields writes only the mapped fields instead of the whole response
+func (f *File) SetFields(m Mapping) {
+	f.fields = m
+}
+
+// Send appends the response to the file
+func (f *File) Send(ctx context.Context, resp model.SurveyResponse) error {
+	var record interface{} = resp
+	if f.fields != nil {
+		record = f.fields.Apply(resp, time.Now())
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("failed to marshal response: %w", err))
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.path, err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// expandPath expands both %VAR% (Windows) and $VAR references
+func expandPath(path string) string {
+	path = windowsEnvRef.ReplaceAllStringFunc(path, func(ref string) string {
+		if v, ok := os.LookupEnv(ref[1 : len(ref)-1]); ok {
+			return v
+		}
+		return ref
+	})
+	return os.ExpandEnv(path)
+}
+
+var windowsEnvRef = regexp.MustCompile(`%[A-Za-z_][A-Za-z0-9_]*%`)
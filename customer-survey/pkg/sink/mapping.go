@@ -0,0 +1,265 @@
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"customer-survey/pkg/model"
+)
+
+// Value types a mapped field can be sent as
+const (
+	ValueString = "string"
+	ValueNumber = "number"
+)
+
+// Field maps one response field to a key in the payload sent to a sink
+type Field struct {
+	Key   string `json:"key"`            // output key, e.g. the Zoho column name
+	Field string `json:"field"`          // source field, see Fields
+	Type  string `json:"type,omitempty"` // "string" or "number"; default depends on the field
+}
+
+// Mapping is the ordered list of fields a sink sends
+type Mapping []Field
+
+// source describes a field that can be mapped
+type source struct {
+	number  bool // natural type is a number; otherwise a string
+	numeric bool // can be sent as a number
+	raw     bool // sent unchanged unless a type is given
+	value   func(resp *model.SurveyResponse, sentAt time.Time) interface{}
+}
+
+func stringField(get func(r *model.SurveyResponse) string) source {
+	return source{value: func(r *model.SurveyResponse, _ time.Time) interface{} { return get(r) }}
+}
+
+func ratingField(key string) source {
+	return source{number: true, numeric: true, value: func(r *model.SurveyResponse, _ time.Time) interface{} {
+		n, _ := r.Answers.Int(key)
+		return n
+	}}
+}
+
+// labelField sends the label stamped from the survey definition; records
+// without labels fall back to the Good/Okay/Bad of a 3-point rating
+func labelField(key string) source {
+	return source{value: func(r *model.SurveyResponse, _ time.Time) interface{} {
+		if l, ok := r.Labels[key]; ok {
+			return l
+		}
+		n, _ := r.Answers.Int(key)
+		return RatingLabelFor(n)
+	}}
+}
+
+// answerField sends an answer as it was given: numbers as numbers, text as text
+func answerField(key string) source {
+	return source{raw: true, numeric: true, value: func(r *model.SurveyResponse, _ time.Time) interface{} { return r.Answers[key] }}
+}
+
+// Times are sent as RFC 3339 strings, or as Unix seconds with type "number"
+func timeField(get func(r *model.SurveyResponse, sentAt time.Time) time.Time) source {
+	return source{numeric: true, value: func(r *model.SurveyResponse, sentAt time.Time) interface{} { return get(r, sentAt) }}
+}
+
+// Prefixes for fields named after a question key of the survey definition
+const (
+	AnswerPrefix = "answers." // answers.<key>: the answer as given
+	LabelPrefix  = "labels."  // labels.<key>: the answer's label, e.g. "Good" or "Promoter"
+)
+
+// Fields lists every fixed source field a mapping can refer to. The
+// *_label fields and sent_at are computed at send time. The rating and note
+// fields read the answers of the default survey; any other question is
+// mapped with AnswerPrefix or LabelPrefix.
+var Fields = map[string]source{
+	"submission_id":            stringField(func(r *model.SurveyResponse) string { return r.SubmissionID }),
+	"survey_id":                stringField(func(r *model.SurveyResponse) string { return r.SurveyID }),
+	"survey_version":           {number: true, numeric: true, value: func(r *model.SurveyResponse, _ time.Time) interface{} { return r.SurveyVersion }},
+	"campaign":                 stringField(func(r *model.SurveyResponse) string { return r.Campaign }),
+	"server_name":              stringField(func(r *model.SurveyResponse) string { return r.ServerName }),
+	"user_name":                stringField(func(r *model.SurveyResponse) string { return r.UserName }),
+	"survey_response":          stringField(func(r *model.SurveyResponse) string { return r.SurveyResponse }),
+	"answers":                  {raw: true, value: func(r *model.SurveyResponse, _ time.Time) interface{} { return r.Answers }},
+	"note":                     stringField(func(r *model.SurveyResponse) string { return r.Answers.String(model.KeyNote) }),
+	"server_performance":       ratingField(model.KeyServerPerformance),
+	"technical_support":        ratingField(model.KeyTechnicalSupport),
+	"overall_support":          ratingField(model.KeyOverallSupport),
+	"server_performance_label": labelField(model.KeyServerPerformance),
+	"technical_support_label":  labelField(model.KeyTechnicalSupport),
+	"overall_support_label":    labelField(model.KeyOverallSupport),
+	"snoozes":                  {number: true, numeric: true, value: func(r *model.SurveyResponse, _ time.Time) interface{} { return r.Snoozes }},
+	"remind_at":                {numeric: true, value: remindAt},
+	"answered_at":              timeField(func(r *model.SurveyResponse, _ time.Time) time.Time { return r.AnsweredAt }),
+	"sent_at":                  timeField(func(_ *model.SurveyResponse, sentAt time.Time) time.Time { return sentAt }),
+}
+
+// remindAt sends the end of a snooze like the other times, and "" without one
+func remindAt(r *model.SurveyResponse, _ time.Time) interface{} {
+	if r.RemindAt == nil {
+		return ""
+	}
+	return *r.RemindAt
+}
+
+// lookup resolves a fixed field or one named after a question key
+func lookup(name string) (source, bool) {
+	if src, ok := Fields[name]; ok {
+		return src, true
+	}
+	if key := strings.TrimPrefix(name, AnswerPrefix); key != name && key != "" {
+		return answerField(key), true
+	}
+	if key := strings.TrimPrefix(name, LabelPrefix); key != name && key != "" {
+		return labelField(key), true
+	}
+	return source{}, false
+}
+
+// WebhookFields is the default webhook payload. With labels set, ratings are
+// sent as "Good"/"Okay"/"Bad" instead of numbers.
+func WebhookFields(labels bool) Mapping {
+	rating := func(name string) string {
+		if labels {
+			return name + "_label"
+		}
+		return name
+	}
+	return Mapping{
+		{Key: "submission_id", Field: "submission_id"}, // same on every resend, use it to de-duplicate rows
+		{Key: "survey_id", Field: "survey_id"},
+		{Key: "survey_version", Field: "survey_version"},
+		{Key: "campaign", Field: "campaign"},
+		{Key: "machine_name", Field: "server_name"},
+		{Key: "username", Field: "user_name"},
+		{Key: "survey_response", Field: "survey_response"},
+		{Key: "server_performance", Field: rating("server_performance")},
+		{Key: "technical_support", Field: rating("technical_support")},
+		{Key: "overall_support", Field: rating("overall_support")},
+		{Key: "note", Field: "note"},
+		{Key: "answers", Field: "answers"},     // every answer by question key, including new questions
+		{Key: "snoozes", Field: "snoozes"},     // Remind Me Later in a row, 0 for other events
+		{Key: "remind_at", Field: "remind_at"}, // when a snoozed survey is shown again
+		{Key: "timestamp", Field: "answered_at"},
+		{Key: "answered_at", Field: "answered_at"},
+		{Key: "sent_at", Field: "sent_at"},
+	}
+}
+
+// CreatorFields is the default mapping onto the Zoho Creator form
+var CreatorFields = Mapping{
+	{Key: "Server_Name", Field: "server_name"},
+	{Key: "User_Name", Field: "user_name"},
+	{Key: "Survey_Response", Field: "survey_response"},
+	{Key: "Server_Performance", Field: "server_performance"},
+	{Key: "Technical_Support", Field: "technical_support"},
+	{Key: "Overall_Support", Field: "overall_support"},
+	{Key: "Additional_Comments", Field: "note"},
+}
+
+// Validate reports unknown fields, unsupported types and duplicate keys
+func (m Mapping) Validate() error {
+	if len(m) == 0 {
+		return fmt.Errorf("fields: mapping is empty")
+	}
+	keys := map[string]bool{}
+	for _, f := range m {
+		if strings.TrimSpace(f.Key) == "" {
+			return fmt.Errorf("fields: key for %q is empty", f.Field)
+		}
+		if keys[f.Key] {
+			return fmt.Errorf("fields: key %q is mapped twice", f.Key)
+		}
+		keys[f.Key] = true
+
+		src, ok := lookup(f.Field)
+		if !ok {
+			return fmt.Errorf("fields: %q has unknown field %q (known: %s, %s<key>, %s<key>)",
+				f.Key, f.Field, strings.Join(fieldNames(), ", "), AnswerPrefix, LabelPrefix)
+		}
+		switch f.Type {
+		case "", ValueString:
+		case ValueNumber:
+			if !src.numeric {
+				return fmt.Errorf("fields: %q cannot send %q as a number", f.Key, f.Field)
+			}
+		default:
+			return fmt.Errorf("fields: %q has unknown type %q (expected %q or %q)", f.Key, f.Type, ValueString, ValueNumber)
+		}
+	}
+	return nil
+}
+
+// Apply builds the payload for resp. sentAt is the time of the current attempt.
+func (m Mapping) Apply(resp model.SurveyResponse, sentAt time.Time) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for _, f := range m {
+		src, ok := lookup(f.Field)
+		if !ok {
+			continue
+		}
+		v := src.value(&resp, sentAt)
+		if src.raw && f.Type == "" {
+			out[f.Key] = v
+			continue
+		}
+		out[f.Key] = convert(v, f.Type, src.number)
+	}
+	return out
+}
+
+// convert turns a field value into the requested output type
+func convert(v interface{}, typ string, number bool) interface{} {
+	if typ == "" {
+		if number {
+			typ = ValueNumber
+		} else {
+			typ = ValueString
+		}
+	}
+	switch v := v.(type) {
+	case int:
+		if typ == ValueString {
+			return strconv.Itoa(v)
+		}
+		return v
+	case float64: // answers read back from the outbox
+		if typ == ValueString {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return v
+	case string:
+		if typ == ValueNumber {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return n
+			}
+		}
+		return v
+	case []interface{}:
+		if typ == ValueString {
+			return model.Text(v)
+		}
+		return v
+	case time.Time:
+		if typ == ValueNumber {
+			return v.Unix()
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return v
+	}
+}
+
+func fieldNames() []string {
+	names := make([]string, 0, len(Fields))
+	for name := range Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,195 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/zoho"
+)
+
+// Sink is a destination for survey responses
+type Sink interface {
+	// Name identifies the sink in logs and errors
+	Name() string
+	// Send delivers one response. It returns nil only when the destination
+	// accepted it; permanent failures are marked with retry.Permanent.
+	Send(ctx context.Context, resp model.SurveyResponse) error
+}
+
+// Sink types accepted in the "type" field of a sink config
+const (
+	TypeWebhook = "webhook" // Zoho Flow (or any JSON) webhook
+	TypeCreator = "creator" // Zoho Creator form via OAuth
+	TypeFile    = "file"    // local JSON-lines file
+)
+
+// Delivery modes selectable with "delivery_mode" in config.json when no
+// explicit "sinks" list is given
+const (
+	ModeWebhook = "webhook" // post to the Zoho Flow webhook URL (default)
+	ModeCreator = "creator" // submit to Zoho Creator via OAuth, no webhook key needed
+)
+
+// ForMode builds the single sink for a delivery mode. For ModeCreator the
+// credentials come from creator, as resolved by pkg/config. A zero timeout
+// keeps the default of 30 seconds.
+func ForMode(mode, webhookURL string, labels bool, creator *zoho.Config, timeout time.Duration, policy retry.Policy) (Sink, error) {
+	seconds := int(timeout / time.Second)
+	switch strings.ToLower(mode) {
+	case "", ModeWebhook:
+		return New(Config{Type: TypeWebhook, Name: "zoho-flow", URL: webhookURL, RatingFormat: ratingFormat(labels), TimeoutSeconds: seconds}, policy)
+	case ModeCreator:
+		if creator == nil {
+			return nil, fmt.Errorf("zoho creator credentials not found: set ZOHO_* environment variables, a zoho_creator section or %s", zoho.SecureConfigFile)
+		}
+		return New(Config{Type: TypeCreator, Name: "zoho-creator", Creator: creator, TimeoutSeconds: seconds}, policy)
+	default:
+		return nil, fmt.Errorf("unknown delivery_mode %q (expected %q or %q)", mode, ModeWebhook, ModeCreator)
+	}
+}
+
+func ratingFormat(labels bool) string {
+	if labels {
+		return RatingLabel
+	}
+	return RatingNumber
+}
+
+// Config describes one entry of the "sinks" list in config.json
+type Config struct {
+	Type     string  `json:"type"`
+	Name     string  `json:"name,omitempty"`
+	Required *bool   `json:"required,omitempty"` // default true
+	Fields   Mapping `json:"fields,omitempty"`   // output keys; default depends on the type
+
+	// webhook
+	URL            string `json:"url,omitempty"`
+	RatingFormat   string `json:"rating_format,omitempty"` // "number" (default) or "label"
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+
+	// creator
+	Creator *zoho.Config `json:"creator,omitempty"`
+
+	// file
+	Path string `json:"path,omitempty"`
+
+	Retry *retry.Config `json:"retry,omitempty"` // overrides the top-level retry policy
+}
+
+// HasRequired reports whether any of configs is required, as Build demands
+func HasRequired(configs []Config) bool {
+	for _, c := range configs {
+		if c.IsRequired() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRequired reports whether a failure of this sink fails the whole submission
+func (c Config) IsRequired() bool {
+	return c.Required == nil || *c.Required
+}
+
+// New creates a single sink from its config. policy is used when the
+// config has no retry section of its own.
+func New(c Config, policy retry.Policy) (Sink, error) {
+	if c.Retry != nil {
+		policy = c.Retry.Policy()
+	}
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+
+	if c.Fields != nil {
+		if err := c.Fields.Validate(); err != nil {
+			return nil, fmt.Errorf("sink %q: %w", name, err)
+		}
+	}
+
+	timeout := 30 * time.Second
+	if c.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	}
+
+	switch strings.ToLower(c.Type) {
+	case TypeWebhook:
+		if c.URL == "" {
+			return nil, fmt.Errorf("sink %q: webhook url is empty", name)
+		}
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return nil, fmt.Errorf("sink %q: webhook url must start with http:// or https://", name)
+		}
+		if c.RatingFormat != "" && c.RatingFormat != RatingNumber && c.RatingFormat != RatingLabel {
+			return nil, fmt.Errorf("sink %q: unknown rating_format %q", name, c.RatingFormat)
+		}
+		w := NewWebhook(name, c.URL, c.RatingFormat == RatingLabel, timeout, policy)
+		if c.Fields != nil {
+			w.SetFields(c.Fields)
+		}
+		return w, nil
+	case TypeCreator:
+		if c.Creator == nil {
+			return nil, fmt.Errorf("sink %q: creator section is missing", name)
+		}
+		if err := c.Creator.Validate(); err != nil {
+			return nil, fmt.Errorf("sink %q: %w", name, err)
+		}
+		auth := zoho.NewAuth(c.Creator)
+		auth.SetTimeout(timeout)
+		cr := NewCreator(name, auth, policy)
+		if c.Fields != nil {
+			cr.SetFields(c.Fields)
+		}
+		return cr, nil
+	case TypeFile:
+		if c.Path == "" {
+			return nil, fmt.Errorf("sink %q: file path is empty", name)
+		}
+		f := NewFile(name, c.Path)
+		if c.Fields != nil {
+			f.SetFields(c.Fields)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("sink %q: unknown type %q", name, c.Type)
+	}
+}
+
+// Build creates the sink for a list of configs. A single required sink is
+// returned as is; anything else is wrapped in a FanOut. At least one sink
+// must be required: a FanOut ignores the failures of optional ones, so
+// without a required sink a response nobody accepted would count as sent.
+func Build(configs []Config, policy retry.Policy) (Sink, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no sinks configured")
+	}
+
+	var targets []Target
+	names := map[string]bool{}
+	for _, c := range configs {
+		s, err := New(c, policy)
+		if err != nil {
+			return nil, err
+		}
+		// Deliveries are tracked per sink name, see Deliver
+		if names[s.Name()] {
+			return nil, fmt.Errorf("sink %q: name is used twice", s.Name())
+		}
+		names[s.Name()] = true
+		targets = append(targets, Target{Sink: s, Required: c.IsRequired()})
+	}
+	if !HasRequired(configs) {
+		return nil, fmt.Errorf("no required sink: at least one sink must have \"required\": true")
+	}
+
+	if len(targets) == 1 && targets[0].Required {
+		return targets[0].Sink, nil
+	}
+	return NewFanOut(targets...), nil
+}
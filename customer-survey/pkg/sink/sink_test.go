@@ -0,0 +1,279 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+	"customer-survey/pkg/zoho"
+)
+
+var noRetry = retry.Policy{MaxAttempts: 1}
+
+type stubSink struct {
+	name string
+	err  error
+	sent int
+}
+
+func (s *stubSink) Name() string { return s.name }
+
+func (s *stubSink) Send(ctx context.Context, resp model.SurveyResponse) error {
+	s.sent++
+	return s.err
+}
+
+func TestFanOutRequiredAndOptional(t *testing.T) {
+	ok := &stubSink{name: "ok"}
+	optional := &stubSink{name: "optional", err: errors.New("disk full")}
+	f := NewFanOut(Target{Sink: ok, Required: true}, Target{Sink: optional})
+
+	if err := f.Send(context.Background(), model.SurveyResponse{}); err != nil {
+		t.Errorf("Optional failure should not fail the fan-out: %v", err)
+	}
+	if ok.sent != 1 || optional.sent != 1 {
+		t.Errorf("Every sink should be called once, got %d and %d", ok.sent, optional.sent)
+	}
+
+	required := &stubSink{name: "required", err: errors.New("timeout")}
+	f = NewFanOut(Target{Sink: ok, Required: true}, Target{Sink: required, Required: true})
+	err := f.Send(context.Background(), model.SurveyResponse{})
+	if err == nil {
+		t.Fatal("Required failure should fail the fan-out")
+	}
+	if retry.IsPermanent(err) {
+		t.Error("Transient failure should not be reported as permanent")
+	}
+
+	rejected := &stubSink{name: "rejected", err: retry.Permanent(errors.New("HTTP 400"))}
+	f = NewFanOut(Target{Sink: rejected, Required: true})
+	if err := f.Send(context.Background(), model.SurveyResponse{}); !retry.IsPermanent(err) {
+		t.Errorf("Only permanent required failures should be permanent, got %v", err)
+	}
+}
+
+func TestDeliverSkipsAcceptingSinks(t *testing.T) {
+	file := &stubSink{name: "file"}
+	webhook := &stubSink{name: "webhook", err: errors.New("timeout")}
+	f := NewFanOut(Target{Sink: file, Required: true}, Target{Sink: webhook, Required: true})
+
+	delivered, err := Deliver(context.Background(), f, model.SurveyResponse{}, nil)
+	if err == nil || len(delivered) != 1 || delivered[0] != "file" {
+		t.Fatalf("Expected only the file to accept, got %v, %v", delivered, err)
+	}
+
+	webhook.err = nil
+	delivered, err = Deliver(context.Background(), f, model.SurveyResponse{}, delivered)
+	if err != nil || len(delivered) != 2 {
+		t.Fatalf("Expected both sinks to have accepted, got %v, %v", delivered, err)
+	}
+	if file.sent != 1 || webhook.sent != 2 {
+		t.Errorf("Only the failed sink should be retried, got file %d, webhook %d", file.sent, webhook.sent)
+	}
+
+	// A single sink is skipped once it accepted
+	if _, err := Deliver(context.Background(), file, model.SurveyResponse{}, []string{"file"}); err != nil || file.sent != 1 {
+		t.Errorf("A delivered sink should not be sent again: %v, %d", err, file.sent)
+	}
+}
+
+func TestWebhookPayloadAndHeaders(t *testing.T) {
+	var got map[string]interface{}
+	var key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("Idempotency-Key")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	resp := model.SurveyResponse{Answers: model.Answers{model.KeyServerPerformance: 3, model.KeyTechnicalSupport: 1}}
+	resp.Stamp()
+
+	w := NewWebhook("flow", srv.URL, true, time.Second, noRetry)
+	if err := w.Send(context.Background(), resp); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if key != resp.SubmissionID || got["submission_id"] != resp.SubmissionID {
+		t.Errorf("Submission ID not sent: header=%q payload=%v", key, got["submission_id"])
+	}
+	if got["server_performance"] != "Good" || got["technical_support"] != "Bad" {
+		t.Errorf("Ratings should be labels: %v", got)
+	}
+	if got["sent_at"] == nil || got["answered_at"] == nil {
+		t.Errorf("Payload should carry both answer and send time: %v", got)
+	}
+}
+
+func TestWebhookRejection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad field", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	w := NewWebhook("flow", srv.URL, false, time.Second, noRetry)
+	if err := w.Send(context.Background(), model.SurveyResponse{}); !retry.IsPermanent(err) {
+		t.Errorf("HTTP 400 should be permanent, got %v", err)
+	}
+}
+
+func TestBuildFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.jsonl")
+	optional := false
+	s, err := Build([]Config{
+		{Type: TypeFile, Path: path},
+		{Type: TypeWebhook, URL: "https://flow.example.com/hook", Required: &optional},
+	}, noRetry)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if _, ok := s.(*FanOut); !ok {
+		t.Fatalf("Expected a fan-out, got %T", s)
+	}
+
+	if _, err := Build([]Config{{Type: "ftp"}}, noRetry); err == nil {
+		t.Error("Unknown sink type should be rejected")
+	}
+	if _, err := Build([]Config{{Type: TypeWebhook, URL: "flow.example.com"}}, noRetry); err == nil {
+		t.Error("Webhook without scheme should be rejected")
+	}
+	if _, err := Build([]Config{{Type: TypeFile, Path: path}, {Type: TypeFile, Path: path + ".2"}}, noRetry); err == nil {
+		t.Error("Two sinks of the same name should be rejected")
+	}
+	if _, err := Build([]Config{{Type: TypeWebhook, URL: "https://flow.example.com/hook", Required: &optional}}, noRetry); err == nil {
+		t.Error("Sinks that are all optional should be rejected")
+	}
+
+	s, _ = Build([]Config{{Type: TypeFile, Path: path}}, noRetry)
+	if err := s.Send(context.Background(), model.SurveyResponse{UserName: "alice"}); err != nil {
+		t.Fatalf("File sink failed: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || len(data) == 0 {
+		t.Errorf("File sink should write the response, err=%v", err)
+	}
+}
+
+func TestForMode(t *testing.T) {
+	creator := &zoho.Config{
+		AccountOwner: "acme", AppLinkName: "survey", FormLinkName: "Responses",
+		ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh", DataCenter: "in",
+	}
+	s, err := ForMode(ModeCreator, "", false, creator, 0, noRetry)
+	if err != nil {
+		t.Fatalf("Creator mode failed: %v", err)
+	}
+	if _, ok := s.(*Creator); !ok {
+		t.Errorf("Expected a Creator sink, got %T", s)
+	}
+
+	incomplete := *creator
+	incomplete.RefreshToken = ""
+	if _, err := ForMode(ModeCreator, "", false, &incomplete, 0, noRetry); err == nil {
+		t.Error("Creator mode without a refresh token should be rejected")
+	}
+
+	s, err = ForMode("", "https://flow.example.com/hook", true, nil, 0, noRetry)
+	if err != nil {
+		t.Fatalf("Webhook mode failed: %v", err)
+	}
+	if w, ok := s.(*Webhook); !ok || !w.labels {
+		t.Errorf("Expected a label webhook sink, got %#v", s)
+	}
+
+	if _, err := ForMode("carrier-pigeon", "", false, nil, 0, noRetry); err == nil {
+		t.Error("Unknown mode should be rejected")
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	resp := model.SurveyResponse{ServerName: "SRV01", Answers: model.Answers{
+		model.KeyServerPerformance: 3, model.KeyNote: "fast", "speed": "2", "reasons": []interface{}{"vpn", "disk"},
+	}}
+	resp.Stamp()
+	sentAt := resp.AnsweredAt.Add(time.Minute)
+
+	m := Mapping{
+		{Key: "Machine", Field: "server_name"},
+		{Key: "Perf", Field: "server_performance", Type: ValueString},
+		{Key: "Perf_Label", Field: "server_performance_label"},
+		{Key: "Sent", Field: "sent_at", Type: ValueNumber},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Valid mapping rejected: %v", err)
+	}
+	got := m.Apply(resp, sentAt)
+	if got["Machine"] != "SRV01" || got["Perf"] != "3" || got["Perf_Label"] != "Good" || got["Sent"] != sentAt.Unix() {
+		t.Errorf("Unexpected mapped payload: %v", got)
+	}
+	if len(got) != len(m) {
+		t.Errorf("Only mapped keys should be sent, got %v", got)
+	}
+
+	// The defaults reproduce the historical payloads
+	if got := WebhookFields(false).Apply(resp, sentAt); got["machine_name"] != "SRV01" || got["server_performance"] != 3 {
+		t.Errorf("Unexpected default webhook payload: %v", got)
+	}
+	if got := CreatorFields.Apply(resp, sentAt); got["Server_Name"] != "SRV01" || got["Additional_Comments"] != "fast" {
+		t.Errorf("Unexpected default Creator payload: %v", got)
+	}
+
+	// Questions added to the survey definition are mapped by key
+	m = Mapping{
+		{Key: "Speed", Field: "answers.speed", Type: ValueNumber},
+		{Key: "Speed_Label", Field: "labels.speed"},
+		{Key: "Reasons", Field: "answers.reasons", Type: ValueString},
+		{Key: "All", Field: "answers"},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Answer mapping rejected: %v", err)
+	}
+	got = m.Apply(resp, sentAt)
+	if got["Speed"] != 2.0 || got["Speed_Label"] != "Okay" || got["Reasons"] != "vpn, disk" {
+		t.Errorf("Unexpected answer payload: %v", got)
+	}
+	if all, ok := got["All"].(model.Answers); !ok || all["note"] != "fast" {
+		t.Errorf("answers should carry the whole answer map, got %v", got["All"])
+	}
+
+	// Labels stamped from the definition win over the 3-point fallback
+	resp.Labels = map[string]string{"speed": "Passive"}
+	if got := m.Apply(resp, sentAt); got["Speed_Label"] != "Passive" {
+		t.Errorf("Expected the stamped label, got %v", got["Speed_Label"])
+	}
+
+	// Remind Me Later events carry the snooze
+	snooze := Mapping{{Key: "Snoozes", Field: "snoozes"}, {Key: "Until", Field: "remind_at"}}
+	if got := snooze.Apply(resp, sentAt); got["Snoozes"] != 0 || got["Until"] != "" {
+		t.Errorf("Unexpected snooze fields without a snooze: %v", got)
+	}
+	until := sentAt.Add(72 * time.Hour)
+	resp.Snoozes, resp.RemindAt = 2, &until
+	if got := snooze.Apply(resp, sentAt); got["Snoozes"] != 2 || got["Until"] != until.Format(time.RFC3339) {
+		t.Errorf("Unexpected snooze fields: %v", got)
+	}
+
+	for _, bad := range []Mapping{
+		{},
+		{{Key: "x", Field: "overall_rating"}},
+		{{Key: "x", Field: "note", Type: ValueNumber}},
+		{{Key: "x", Field: "note", Type: "bool"}},
+		{{Key: "x", Field: "note"}, {Key: "x", Field: "user_name"}},
+		{{Key: "", Field: "note"}},
+		{{Key: "x", Field: "answers."}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Mapping %v should be rejected", bad)
+		}
+	}
+
+	if _, err := Build([]Config{{Type: TypeFile, Path: "out.jsonl", Fields: Mapping{{Key: "x", Field: "feedback"}}}}, noRetry); err == nil {
+		t.Error("Build should validate field mappings")
+	}
+}
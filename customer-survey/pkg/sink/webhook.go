@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"customer-survey/pkg/model"
+	"customer-survey/pkg/retry"
+)
+
+// Rating formats for webhook payloads
+const (
+	RatingNumber = "number" // 1, 2, 3
+	RatingLabel  = "label"  // "Bad", "Okay", "Good"
+)
+
+// Webhook posts responses as JSON to a Zoho Flow (or compatible) webhook
+type Webhook struct {
+	name   string
+	url    string
+	labels bool
+	fields Mapping
+	client *http.Client
+	policy retry.Policy
+}
+
+// NewWebhook creates a webhook sink sending WebhookFields. With labels set,
+// ratings are sent as "Good"/"Okay"/"Bad" instead of numbers.
+func NewWebhook(name, url string, labels bool, timeout time.Duration, policy retry.Policy) *Webhook {
+	return &Webhook{
+		name:   name,
+		url:    url,
+		labels: labels,
+		fields: WebhookFields(labels),
+		client: &http.Client{Timeout: timeout},
+		policy: policy,
+	}
+}
+
+func (w *Webhook) Name() string { return w.name }
+
+// SetFields replaces the default payload mapping
+func (w *Webhook) SetFields(m Mapping) {
+	w.fields = m
+}
+
+// Send posts the response, retrying transient failures according to the policy
+func (w *Webhook) Send(ctx context.Context, resp model.SurveyResponse) error {
+	return retry.Do(ctx, w.policy, func(ctx context.Context, attempt int) error {
+		body, err := json.Marshal(w.fields.Apply(resp, time.Now()))
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("failed to marshal payload: %w", err))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(fmt.Errorf("failed to create request: %w", err))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "CustomerSurvey/2.0")
+		req.Header.Set("Idempotency-Key", resp.SubmissionID)
+
+		res, err := w.client.Do(req)
+		if err != nil {
+			log.Printf("[%s] Attempt %d/%d failed: %v", w.name, attempt, w.policy.MaxAttempts, err)
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+		if err := retry.CheckResponse(res, resBody); err != nil {
+			log.Printf("[%s] Attempt %d/%d rejected: %v", w.name, attempt, w.policy.MaxAttempts, err)
+			return fmt.Errorf("webhook returned %w", err)
+		}
+
+		log.Printf("[%s] Delivered %s (HTTP %d)", w.name, resp.SubmissionID, res.StatusCode)
+		return nil
+	})
+}
+
+// RatingLabelFor converts a 1-3 rating into the label shown in the UI
+func RatingLabelFor(r int) string {
+	switch r {
+	case 3:
+		return "Good"
+	case 2:
+		return "Okay"
+	case 1:
+		return "Bad"
+	default:
+		return "Unknown"
+	}
+}
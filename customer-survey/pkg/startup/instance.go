@@ -0,0 +1,127 @@
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"customer-survey/pkg/appdata"
+)
+
+// InstanceFile in appdata.Dir names the survey process of the user that
+// is running, so a second launch (an RDS reconnect, a second shell) does
+// not prompt again
+const InstanceFile = "instance.lock"
+
+// StaleInstanceAge is how old an instance lock may get before it is taken
+// over even though its process seems to run; by then the PID is likely
+// reused, or it belongs to another host sharing the AppData folder
+var StaleInstanceAge = 12 * time.Hour
+
+// startSlack is how far the creation time of a process may move between
+// reads; on Linux it is derived from the boot time, which follows clock
+// adjustments
+const startSlack = time.Second
+
+// Instance is the content of InstanceFile
+type Instance struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+	Created time.Time `json:"created,omitempty"` // when the OS created the process, to tell a reused PID apart
+}
+
+// RunningError is returned by AcquireInstance while another instance holds the lock
+type RunningError struct {
+	Instance
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("survey already running (PID %d on %s since %s)", e.PID, e.Host, e.Started.Format(time.RFC3339))
+}
+
+// InstancePath returns the path of the instance lock
+func InstancePath() string {
+	return filepath.Join(appdata.Dir(), InstanceFile)
+}
+
+// AcquireInstance makes this process the user's running survey. It returns
+// a *RunningError if another one is, and takes over a lock whose process
+// is gone or that is older than StaleInstanceAge. Call release when done;
+// a lock left by a crash is detected as stale.
+func AcquireInstance() (release func() error, err error) {
+	unlock, err := appdata.LockFile(InstancePath() + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if held, ok := readInstance(); ok && !held.stale(time.Now()) {
+		return nil, &RunningError{held}
+	}
+
+	host, _ := os.Hostname()
+	me := Instance{PID: os.Getpid(), Host: host, Started: time.Now()}
+	me.Created, _ = processStarted(me.PID)
+	data, err := json.Marshal(me)
+	if err != nil {
+		return nil, err
+	}
+	if err := appdata.WriteFileAtomic(InstancePath(), data, 0644); err != nil {
+		return nil, err
+	}
+	return func() error {
+		unlock, err := appdata.LockFile(InstancePath() + ".lock")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		// Leave a lock alone that another instance took over meanwhile
+		if held, ok := readInstance(); ok && (held.PID != me.PID || held.Host != me.Host) {
+			return nil
+		}
+		if err := os.Remove(InstancePath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}, nil
+}
+
+// readInstance reads the instance lock; a damaged one counts as absent
+func readInstance() (Instance, bool) {
+	var held Instance
+	data, err := os.ReadFile(InstancePath())
+	if err != nil || json.Unmarshal(data, &held) != nil || held.PID == 0 {
+		return Instance{}, false
+	}
+	return held, true
+}
+
+// stale reports whether the instance no longer runs: no process has its
+// PID, or the one that has was created at another time and so reuses it.
+// Processes of another host cannot be checked, so their lock only goes
+// stale with age.
+func (i Instance) stale(now time.Time) bool {
+	if now.Sub(i.Started) > StaleInstanceAge {
+		return true
+	}
+	if host, _ := os.Hostname(); i.Host != host {
+		return false
+	}
+	created, ok := processStarted(i.PID)
+	switch {
+	case !ok:
+		return true
+	case created.IsZero():
+		return false
+	case !i.Created.IsZero():
+		d := created.Sub(i.Created)
+		return d > startSlack || d < -startSlack
+	default:
+		// A lock that does not record the creation time: the process that
+		// wrote it was created before it
+		return created.After(i.Started.Add(startSlack))
+	}
+}
@@ -0,0 +1,87 @@
+package startup
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/appdata"
+)
+
+func TestSingleInstance(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	release, err := AcquireInstance()
+	if err != nil {
+		t.Fatalf("Failed to acquire instance lock: %v", err)
+	}
+	var running *RunningError
+	if _, err := AcquireInstance(); !errors.As(err, &running) || running.PID != os.Getpid() {
+		t.Fatalf("A second instance should be refused, got %v", err)
+	}
+	if err := release(); err != nil {
+		t.Fatalf("Failed to release instance lock: %v", err)
+	}
+	if _, err := os.Stat(InstancePath()); !os.IsNotExist(err) {
+		t.Error("Release should remove the instance lock")
+	}
+	release, err = AcquireInstance()
+	if err != nil {
+		t.Fatalf("Failed to acquire instance lock after release: %v", err)
+	}
+	defer release()
+}
+
+func TestStaleInstance(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	host, _ := os.Hostname()
+	if err := os.MkdirAll(appdata.Dir(), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+
+	created, ok := processStarted(os.Getpid())
+	if !ok || created.IsZero() {
+		t.Fatalf("Failed to read the creation time of this process")
+	}
+
+	// A process that has exited
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("Failed to run process: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		held  Instance
+		stale bool
+	}{
+		{"exited process", Instance{PID: exited.Process.Pid, Host: host, Started: time.Now()}, true},
+		{"running process", Instance{PID: os.Getpid(), Host: host, Started: time.Now(), Created: created}, false},
+		{"reused PID", Instance{PID: os.Getpid(), Host: host, Started: time.Now(), Created: created.Add(-time.Hour)}, true},
+		{"lock without creation time", Instance{PID: os.Getpid(), Host: host, Started: time.Now()}, false},
+		{"lock older than the process", Instance{PID: os.Getpid(), Host: host, Started: created.Add(-time.Hour)}, true},
+		{"too old", Instance{PID: os.Getpid(), Host: host, Started: time.Now().Add(-StaleInstanceAge - time.Hour)}, true},
+		{"other host", Instance{PID: exited.Process.Pid, Host: host + "-other", Started: time.Now()}, false},
+	} {
+		data, _ := json.Marshal(tc.held)
+		if err := appdata.WriteFileAtomic(InstancePath(), data, 0644); err != nil {
+			t.Fatalf("Failed to write instance lock: %v", err)
+		}
+		release, err := AcquireInstance()
+		if tc.stale != (err == nil) {
+			t.Errorf("%s: expected stale %v, got %v", tc.name, tc.stale, err)
+		}
+		if err == nil {
+			// Taken over by another instance meanwhile: release leaves it alone
+			other, _ := json.Marshal(Instance{PID: os.Getpid() + 1, Host: host, Started: time.Now()})
+			appdata.WriteFileAtomic(InstancePath(), other, 0644)
+			release()
+			if _, err := os.Stat(InstancePath()); err != nil {
+				t.Errorf("%s: release removed another instance's lock", tc.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MachinePolicyFile is the machine-wide policy in the machine folder
+// (%ProgramData%\CustomerSurvey on Windows). It is read before the user's
+// state and beats it; see MachinePolicy.
+const MachinePolicyFile = "policy.json"
+
+// MachinePolicyEnv overrides the path of the machine policy
+const MachinePolicyEnv = "CUSTOMER_SURVEY_POLICY"
+
+// More reasons of a Decision, from the machine policy. The -reset option
+// does not override them. ReasonPolicyError fails closed on purpose: a
+// policy meant to keep a fleet quiet must not prompt everybody because of
+// a typo.
+const (
+	ReasonDisabled    = "disabled"     // the machine policy turns the survey off
+	ReasonNotInGroup  = "not_in_group" // the user is in none of the machine policy's groups
+	ReasonPolicyError = "policy_error" // the machine policy could not be read; nothing is shown
+)
+
+// MachinePolicy lets administrators steer every user of a machine without
+// touching their AppData folders. In order of precedence:
+//   - disabled: nobody is asked
+//   - groups: only members of one of these groups are asked
+//   - reset_at: from this time on, choices users made before it no longer
+//     count, so everybody is asked again (once; later choices count as usual)
+//
+// Only then the user's own state and the cadence policy apply.
+type MachinePolicy struct {
+	Disabled bool       `json:"disabled,omitempty"`
+	Groups   []string   `json:"groups,omitempty"`   // "DOMAIN\\Group", "Group" or a SID, any case
+	ResetAt  *time.Time `json:"reset_at,omitempty"` // RFC 3339
+}
+
+// MachinePolicyPath returns where the machine policy is read from
+func MachinePolicyPath() string {
+	if path := os.Getenv(MachinePolicyEnv); path != "" {
+		return path
+	}
+	return filepath.Join(machineDir(), MachinePolicyFile)
+}
+
+// LoadMachinePolicy reads the machine policy; nil if there is none. While
+// it returns an error nobody is asked (ReasonPolicyError); the error is
+// logged at startup and reported by validate-config.
+func LoadMachinePolicy() (*MachinePolicy, error) {
+	p, err := ReadMachinePolicy(MachinePolicyPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ReadMachinePolicy reads a machine policy file, rejecting unknown keys
+func ReadMachinePolicy(path string) (*MachinePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p MachinePolicy
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &p, nil
+}
+
+// MachinePolicyStatus describes the machine policy in effect for status
+// output, "" if there is none
+func MachinePolicyStatus() string {
+	machine, err := LoadMachinePolicy()
+	switch {
+	case err != nil:
+		return fmt.Sprintf("machine policy unreadable: %v", err)
+	case machine == nil:
+		return ""
+	}
+	return fmt.Sprintf("machine policy %s: %s", MachinePolicyPath(), machine)
+}
+
+// check applies disabled and groups, returning the decision that stops the
+// survey, if any
+func (p *MachinePolicy) check() (Decision, bool) {
+	if p == nil {
+		return Decision{}, false
+	}
+	if p.Disabled {
+		return Decision{Reason: ReasonDisabled, Enforced: true,
+			Message: fmt.Sprintf("Survey disabled by machine policy (%s)", MachinePolicyPath())}, true
+	}
+	if len(p.Groups) > 0 && !inGroup(p.Groups, userGroups()) {
+		return Decision{Reason: ReasonNotInGroup, Enforced: true,
+			Message: fmt.Sprintf("User is not in the machine policy's groups (%s)", strings.Join(p.Groups, ", "))}, true
+	}
+	return Decision{}, false
+}
+
+// current returns r as it stands under reset_at: once reset_at has passed,
+// a record not reset for it yet is empty. update stores the reset along
+// with the user's next choice.
+func (p *MachinePolicy) current(r Record, now time.Time) Record {
+	if p == nil || p.ResetAt == nil || now.Before(*p.ResetAt) {
+		return r
+	}
+	if r.ResetFor != nil && !r.ResetFor.Before(*p.ResetAt) {
+		return r
+	}
+	return Record{ResetFor: p.ResetAt}
+}
+
+// String describes the policy for status output
+func (p *MachinePolicy) String() string {
+	var parts []string
+	if p.Disabled {
+		parts = append(parts, "disabled")
+	}
+	if len(p.Groups) > 0 {
+		parts = append(parts, "groups "+strings.Join(p.Groups, ", "))
+	}
+	if p.ResetAt != nil {
+		parts = append(parts, "reset at "+p.ResetAt.Format(time.RFC3339))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no overrides")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// inGroup reports whether any of the user's groups is one of want
+func inGroup(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if strings.EqualFold(strings.TrimSpace(w), h) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package startup
+
+import "os/user"
+
+// machineDir stands in for %ProgramData%\CustomerSurvey
+func machineDir() string {
+	return "/etc/customer-survey"
+}
+
+// userGroups lists the user's groups by name and id
+func userGroups() []string {
+	u, err := user.Current()
+	if err != nil {
+		return nil
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, id := range ids {
+		names = append(names, id)
+		if g, err := user.LookupGroupId(id); err == nil {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
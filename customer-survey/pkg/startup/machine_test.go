@@ -0,0 +1,91 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setMachinePolicy points the machine policy at a file with content
+func setMachinePolicy(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), MachinePolicyFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write machine policy: %v", err)
+	}
+	t.Setenv(MachinePolicyEnv, path)
+}
+
+func TestMachinePolicyPrecedence(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv(MachinePolicyEnv, filepath.Join(t.TempDir(), "none.json"))
+	s := For("fleet")
+
+	if d := s.Evaluate(time.Now()); !d.Show || d.Enforced {
+		t.Errorf("Without a machine policy the survey should be shown, got %+v", d)
+	}
+
+	setMachinePolicy(t, `{"disabled": true, "groups": ["no-such-group"]}`)
+	if d := s.Evaluate(time.Now()); d.Show || d.Reason != ReasonDisabled || !d.Enforced {
+		t.Errorf("Expected %s, got %+v", ReasonDisabled, d)
+	}
+	if status := s.Status(); !strings.Contains(status, "machine policy") || !strings.Contains(status, "disabled") {
+		t.Errorf("Status should show the machine policy: %s", status)
+	}
+
+	setMachinePolicy(t, `{"groups": ["no-such-group"]}`)
+	if d := s.Evaluate(time.Now()); d.Show || d.Reason != ReasonNotInGroup {
+		t.Errorf("Expected %s, got %+v", ReasonNotInGroup, d)
+	}
+	if groups := userGroups(); len(groups) > 0 {
+		setMachinePolicy(t, `{"groups": ["no-such-group", "`+strings.ToUpper(groups[len(groups)-1])+`"]}`)
+		if d := s.Evaluate(time.Now()); !d.Show {
+			t.Errorf("A member of the groups (%v) should be asked, got %+v", groups, d)
+		}
+	}
+
+	setMachinePolicy(t, `{"disabled": "yes"}`)
+	if d := s.Evaluate(time.Now()); d.Show || d.Reason != ReasonPolicyError {
+		t.Errorf("Expected %s for a malformed policy, got %+v", ReasonPolicyError, d)
+	}
+}
+
+func TestMachinePolicyReset(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv(MachinePolicyEnv, filepath.Join(t.TempDir(), "none.json"))
+	s := For("fleet")
+
+	if err := s.MarkNoThanks(); err != nil {
+		t.Fatalf("Failed to mark NoThanks: %v", err)
+	}
+
+	// A reset still ahead changes nothing yet
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	setMachinePolicy(t, `{"reset_at": "`+future+`"}`)
+	if d := s.Evaluate(time.Now()); d.Reason != ReasonOptedOut {
+		t.Errorf("Expected %s before reset_at, got %+v", ReasonOptedOut, d)
+	}
+
+	past := time.Now().Add(-time.Second).Format(time.RFC3339)
+	setMachinePolicy(t, `{"reset_at": "`+past+`"}`)
+	if d := s.Evaluate(time.Now()); !d.Show {
+		t.Errorf("Earlier choices should not count after reset_at, got %+v", d)
+	}
+
+	// Choices after the reset count again
+	if err := s.MarkSurveyDone(); err != nil {
+		t.Fatalf("Failed to mark survey as done: %v", err)
+	}
+	if d := s.Evaluate(time.Now()); d.Reason != ReasonDone {
+		t.Errorf("Expected %s after answering, got %+v", ReasonDone, d)
+	}
+	st, err := LoadStore()
+	if err != nil {
+		t.Fatalf("Failed to load state store: %v", err)
+	}
+	if r := st.Campaigns["fleet"]; r.NoThanksAt != nil || r.ResetFor == nil {
+		t.Errorf("The reset should be stored with the next choice: %+v", r)
+	}
+}
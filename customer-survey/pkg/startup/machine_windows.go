@@ -0,0 +1,40 @@
+package startup
+
+import (
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// machineDir is %ProgramData%\CustomerSurvey
+func machineDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "CustomerSurvey")
+}
+
+// userGroups lists the enabled groups of the process token, each as
+// "DOMAIN\Name", "Name" and SID
+func userGroups() []string {
+	groups, err := windows.GetCurrentProcessToken().GetTokenGroups()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, g := range groups.AllGroups() {
+		if g.Attributes&windows.SE_GROUP_ENABLED == 0 {
+			continue // deny-only, e.g. Administrators without elevation
+		}
+		names = append(names, g.Sid.String())
+		if account, domain, _, err := g.Sid.LookupAccount(""); err == nil {
+			names = append(names, account)
+			if domain != "" {
+				names = append(names, domain+`\`+account)
+			}
+		}
+	}
+	return names
+}
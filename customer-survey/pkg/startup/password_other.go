@@ -0,0 +1,10 @@
+//go:build !windows
+
+package startup
+
+import "time"
+
+// passwordLastSet is unknown outside Windows
+func passwordLastSet() time.Time {
+	return time.Time{}
+}
@@ -0,0 +1,48 @@
+package startup
+
+import (
+	"os"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// userInfo1 is USER_INFO_1 of the NetUserGetInfo API
+type userInfo1 struct {
+	Name        *uint16
+	Password    *uint16
+	PasswordAge uint32 // seconds since the password was last set
+	Priv        uint32
+	HomeDir     *uint16
+	Comment     *uint16
+	Flags       uint32
+	ScriptPath  *uint16
+}
+
+// passwordLastSet asks the account's domain controller (or the local
+// machine for local accounts) how old the user's password is
+func passwordLastSet() time.Time {
+	user := os.Getenv("USERNAME")
+	if user == "" {
+		return time.Time{}
+	}
+	var server *uint16
+	if domain := os.Getenv("USERDOMAIN"); domain != "" && !strings.EqualFold(domain, os.Getenv("COMPUTERNAME")) {
+		if dc := os.Getenv("LOGONSERVER"); dc != "" {
+			server, _ = windows.UTF16PtrFromString(dc)
+		}
+	}
+	name, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return time.Time{}
+	}
+	var buf *byte
+	if err := windows.NetUserGetInfo(server, name, 1, &buf); err != nil {
+		return time.Time{}
+	}
+	defer windows.NetApiBufferFree(buf)
+	info := (*userInfo1)(unsafe.Pointer(buf))
+	return time.Now().Add(-time.Duration(info.PasswordAge) * time.Second)
+}
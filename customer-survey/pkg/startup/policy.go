@@ -0,0 +1,176 @@
+package startup
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policy is the "cadence" section of the configuration: how often a user
+// may be asked. The zero Policy is the original one-shot behaviour: never
+// ask again after an answer, keep asking until the user answers or opts out.
+type Policy struct {
+	CooldownDays          int `json:"cooldown_days,omitempty"`           // ask again this many days after an answer, 0 for never
+	MaxIgnoredPrompts     int `json:"max_ignored_prompts,omitempty"`     // stop after this many prompts in a row got no answer, 0 for no limit
+	MaxConsecutiveSnoozes int `json:"max_consecutive_snoozes,omitempty"` // stop after this many Remind Me Later in a row, 0 for no limit
+
+	// SnoozeDays is how many days the 1st, 2nd, ... Remind Me Later in a
+	// row waits; the last entry repeats. Without it every snooze waits
+	// RemindDuration.
+	SnoozeDays []int `json:"snooze_days,omitempty"`
+}
+
+// Validate reports negative settings
+func (p Policy) Validate() error {
+	switch {
+	case p.CooldownDays < 0:
+		return fmt.Errorf("cooldown_days: must not be negative, got %d", p.CooldownDays)
+	case p.MaxIgnoredPrompts < 0:
+		return fmt.Errorf("max_ignored_prompts: must not be negative, got %d", p.MaxIgnoredPrompts)
+	case p.MaxConsecutiveSnoozes < 0:
+		return fmt.Errorf("max_consecutive_snoozes: must not be negative, got %d", p.MaxConsecutiveSnoozes)
+	}
+	for i, days := range p.SnoozeDays {
+		if days < 1 {
+			return fmt.Errorf("snooze_days[%d]: must be at least 1, got %d", i, days)
+		}
+	}
+	return nil
+}
+
+// SnoozeDuration returns how long the nth Remind Me Later in a row waits
+func (p Policy) SnoozeDuration(n int) time.Duration {
+	if len(p.SnoozeDays) == 0 {
+		return RemindDuration
+	}
+	if n > len(p.SnoozeDays) {
+		n = len(p.SnoozeDays)
+	}
+	if n < 1 {
+		n = 1
+	}
+	return time.Duration(p.SnoozeDays[n-1]) * 24 * time.Hour
+}
+
+// Snooze is the outcome of one Remind Me Later
+type Snooze struct {
+	Number   int           // snoozes in a row, including this one
+	Duration time.Duration // how long the survey waits
+	Until    time.Time     // when it is shown again
+	Last     bool          // max_consecutive_snoozes is reached: it is not shown again
+}
+
+// Wait describes the snooze for the user, e.g. "3 days"
+func (s Snooze) Wait() string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch d := s.Duration; {
+	case d >= 24*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	default:
+		return plural(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
+
+// Reasons of a Decision. They are stable so logs and scripts can match them.
+const (
+	ReasonShow       = "show"        // nothing stops the prompt
+	ReasonDone       = "done"        // answered, and no cooldown is configured
+	ReasonCooldown   = "cooldown"    // answered less than cooldown_days ago
+	ReasonOptedOut   = "opted_out"   // No Thanks
+	ReasonSnoozed    = "snoozed"     // within the Remind Me Later window
+	ReasonMaxSnoozes = "max_snoozes" // snoozed max_consecutive_snoozes times in a row
+	ReasonMaxIgnored = "max_ignored" // max_ignored_prompts prompts in a row got no answer
+	ReasonStateError = "state_error" // the state store could not be read
+)
+
+// Decision is the outcome of evaluating a State against its Policy
+type Decision struct {
+	Show    bool
+	Reason  string    // one of the Reason constants
+	Message string    // human-readable, for logs and the status display
+	Until   time.Time // when the reason stops applying, zero if it does not by itself
+	Err     error     // set with ReasonStateError
+
+	// Enforced is set for decisions of the machine policy, which -reset
+	// does not override
+	Enforced bool
+}
+
+func (d Decision) String() string {
+	return fmt.Sprintf("%s [%s]", d.Message, d.Reason)
+}
+
+// WithPolicy returns s evaluated against p
+func (s State) WithPolicy(p Policy) State {
+	s.Policy = p
+	return s
+}
+
+// Evaluate decides whether the survey is shown at now, and why. The
+// machine policy comes first, then the user's state and the Policy.
+func (s State) Evaluate(now time.Time) Decision {
+	machine, err := LoadMachinePolicy()
+	if err != nil {
+		return Decision{Reason: ReasonPolicyError, Enforced: true, Message: fmt.Sprintf("Error reading machine policy: %v", err)}
+	}
+	if d, stop := machine.check(); stop {
+		return d
+	}
+
+	r, err := s.record(machine)
+	if err != nil {
+		return Decision{Reason: ReasonStateError, Message: fmt.Sprintf("Error reading survey state: %v", err), Err: err}
+	}
+
+	if r.DoneAt != nil {
+		if s.Policy.CooldownDays == 0 {
+			return Decision{Reason: ReasonDone, Message: "Survey completed"}
+		}
+		next := r.DoneAt.AddDate(0, 0, s.Policy.CooldownDays)
+		if now.Before(next) {
+			return Decision{Reason: ReasonCooldown, Until: next,
+				Message: fmt.Sprintf("Survey completed, next survey after %s", next.Format(time.RFC3339))}
+		}
+	}
+
+	if r.NoThanksAt != nil {
+		return Decision{Reason: ReasonOptedOut, Message: "User opted out (No Thanks)"}
+	}
+
+	if r.RemindAt != nil && now.Before(*r.RemindAt) {
+		return Decision{Reason: ReasonSnoozed, Until: *r.RemindAt,
+			Message: fmt.Sprintf("Remind me later (until %s)", r.RemindAt.Format(time.RFC3339))}
+	}
+
+	if max := s.Policy.MaxConsecutiveSnoozes; max > 0 && r.Snoozes >= max {
+		return Decision{Reason: ReasonMaxSnoozes, Message: fmt.Sprintf("Snoozed %d times in a row, not asking again", r.Snoozes)}
+	}
+	if max := s.Policy.MaxIgnoredPrompts; max > 0 && r.Ignored >= max {
+		return Decision{Reason: ReasonMaxIgnored, Message: fmt.Sprintf("Prompt ignored %d times in a row, not asking again", r.Ignored)}
+	}
+
+	return Decision{Show: true, Reason: ReasonShow, Message: "Survey should be shown"}
+}
+
+// Counters returns the prompt counters; all zero before the first prompt
+func (s State) Counters() (Counters, error) {
+	r, err := s.Record()
+	return r.Counters, err
+}
+
+// RecordPrompt counts a prompt being shown. Until the user makes a choice
+// it counts as ignored.
+func (s State) RecordPrompt() error {
+	return s.update(func(r *Record) {
+		now := time.Now()
+		r.Prompts++
+		r.Ignored++
+		r.LastPrompt = &now
+	})
+}
@@ -0,0 +1,93 @@
+package startup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyCooldown(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	s := For("quarterly").WithPolicy(Policy{CooldownDays: 90})
+
+	if err := s.MarkSurveyDone(); err != nil {
+		t.Fatalf("Failed to mark survey as done: %v", err)
+	}
+	now := time.Now()
+	if d := s.Evaluate(now); d.Show || d.Reason != ReasonCooldown || d.Until.Sub(now) < 89*24*time.Hour {
+		t.Errorf("Expected a 90-day cooldown, got %+v", d)
+	}
+	if d := s.Evaluate(now.AddDate(0, 0, 91)); !d.Show || d.Reason != ReasonShow {
+		t.Errorf("Expected the survey again after the cooldown, got %+v", d)
+	}
+	if d := For("quarterly").Evaluate(now.AddDate(1, 0, 0)); d.Show || d.Reason != ReasonDone {
+		t.Errorf("Without a cooldown an answer should be final, got %+v", d)
+	}
+}
+
+func TestPolicyLimits(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv("TEST_REMIND_MINUTES", "0")
+	s := For("limits").WithPolicy(Policy{MaxIgnoredPrompts: 3, MaxConsecutiveSnoozes: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := s.RecordPrompt(); err != nil {
+			t.Fatalf("Failed to record prompt: %v", err)
+		}
+	}
+	// Answering a prompt with Remind Me Later does not count it as ignored
+	s.MarkRemindLater()
+	s.RecordPrompt()
+	s.RecordPrompt()
+	if d := s.Evaluate(time.Now()); !d.Show {
+		t.Errorf("Two ignored prompts in a row are below the limit, got %+v", d)
+	}
+	s.RecordPrompt()
+	if d := s.Evaluate(time.Now()); d.Show || d.Reason != ReasonMaxIgnored {
+		t.Errorf("Expected %s after 3 ignored prompts, got %+v", ReasonMaxIgnored, d)
+	}
+
+	s.MarkRemindLater()
+	if c, _ := s.Counters(); c.Snoozes != 2 || c.Ignored != 0 || c.Prompts != 5 {
+		t.Errorf("Unexpected counters: %+v", c)
+	}
+	if d := s.Evaluate(time.Now()); d.Show || d.Reason != ReasonMaxSnoozes {
+		t.Errorf("Expected %s after 2 snoozes in a row, got %+v", ReasonMaxSnoozes, d)
+	}
+
+	// An answer starts the counts over
+	s.MarkSurveyDone()
+	if c, _ := s.Counters(); c.Snoozes != 0 || c.Prompts != 0 {
+		t.Errorf("Counters should be reset by an answer: %+v", c)
+	}
+}
+
+func TestSnoozeSchedule(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	s := For("snooze").WithPolicy(Policy{SnoozeDays: []int{1, 3, 7}, MaxConsecutiveSnoozes: 4})
+
+	for i, want := range []struct {
+		wait string
+		last bool
+	}{{"1 day", false}, {"3 days", false}, {"7 days", false}, {"7 days", true}} {
+		snooze, err := s.RemindLater()
+		if err != nil {
+			t.Fatalf("Failed to snooze: %v", err)
+		}
+		if snooze.Number != i+1 || snooze.Wait() != want.wait || snooze.Last != want.last {
+			t.Errorf("Snooze %d: got %+v (%s), want %s, last %v", i+1, snooze, snooze.Wait(), want.wait, want.last)
+		}
+		if d := time.Until(snooze.Until) - snooze.Duration; d < -time.Minute || d > time.Minute {
+			t.Errorf("Snooze %d ends at %s, not in %s", i+1, snooze.Until, snooze.Wait())
+		}
+	}
+
+	// An answer starts the schedule over
+	s.MarkSurveyDone()
+	if snooze, _ := s.RemindLater(); snooze.Number != 1 || snooze.Wait() != "1 day" {
+		t.Errorf("Expected the first step after an answer, got %+v", snooze)
+	}
+
+	if err := (Policy{SnoozeDays: []int{1, 0}}).Validate(); err == nil {
+		t.Error("A snooze of 0 days should be rejected")
+	}
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package startup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the start time in /proc/<pid>/stat
+const clockTicks = 100
+
+// processStarted returns when the running process with the PID was
+// created, read from /proc; ok is false if it does not run. Without /proc
+// the time is zero and only the PID is checked.
+func processStarted(pid int) (created time.Time, ok bool) {
+	if err := syscall.Kill(pid, 0); err != nil && err != syscall.EPERM {
+		return time.Time{}, false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return time.Time{}, true
+	}
+	// The command name in parentheses may contain spaces; the start time is
+	// the 22nd field, the 20th after it
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	if len(fields) < 20 {
+		return time.Time{}, true
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	boot, ok := bootTime()
+	if err != nil || !ok {
+		return time.Time{}, true
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks), true
+}
+
+// bootTime reads the btime line of /proc/stat
+func bootTime() (time.Time, bool) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v, found := strings.CutPrefix(s.Text(), "btime "); found {
+			sec, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			return time.Unix(sec, 0), err == nil
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,31 @@
+package startup
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports for a running process
+const stillActive = 259
+
+// processStarted returns when the running process with the PID was
+// created; ok is false if it has exited or cannot be queried. A user can
+// always query their own survey, so a process that refuses
+// (ERROR_ACCESS_DENIED) is someone else's that reuses the PID.
+func processStarted(pid int) (created time.Time, ok bool) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err == nil && code != stillActive {
+		return time.Time{}, false
+	}
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, creation.Nanoseconds()), true
+}
@@ -1,10 +1,10 @@
 package startup
 
 import (
-	"fmt"
 	"os"
-	"path/filepath"
 	"time"
+
+	"customer-survey/pkg/appdata"
 )
 
 // RemindDuration is the default duration to use when the user selects "Remind Me Later".
@@ -12,171 +12,194 @@ import (
 // the TEST_REMIND_MINUTES environment variable or by changing this variable.
 var RemindDuration = 7 * 24 * time.Hour
 
-// GetAppDataDir returns the per-user AppData folder for the survey app
-func GetAppDataDir() string {
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		appData = os.Getenv("USERPROFILE")
+// State is the done / no-thanks / remind-later state of one campaign, kept
+// in the state store (see LoadStore). The zero State is the state kept
+// before campaigns existed, with the one-shot Policy; the package-level
+// functions use it.
+type State struct {
+	Campaign string
+	Policy   Policy // see Evaluate
+}
+
+// For returns the state of a campaign; "" is the state without a campaign
+func For(campaign string) State {
+	return State{Campaign: campaign}
+}
+
+var legacy State
+
+// Record returns the state's record from the store, as the machine
+// policy's reset_at leaves it
+func (s State) Record() (Record, error) {
+	machine, err := LoadMachinePolicy()
+	if err != nil {
+		return Record{}, err
 	}
-	return filepath.Join(appData, "CustomerSurvey")
+	return s.record(machine)
 }
 
-// ensureAppDataDir creates the AppData directory if it doesn't exist
-func ensureAppDataDir() error {
-	dir := GetAppDataDir()
-	return os.MkdirAll(dir, 0755)
+func (s State) record(machine *MachinePolicy) (Record, error) {
+	st, err := LoadStore()
+	if err != nil {
+		return Record{}, err
+	}
+	return machine.current(*st.Record(s.Campaign), time.Now()), nil
 }
 
-// IsSurveyDone checks if done.flag exists
-func IsSurveyDone() bool {
-	flagPath := filepath.Join(GetAppDataDir(), "done.flag")
-	_, err := os.Stat(flagPath)
-	return err == nil
+// IsSurveyDone checks if the survey was answered
+func (s State) IsSurveyDone() bool {
+	r, err := s.Record()
+	return err == nil && r.DoneAt != nil
 }
 
-// IsNoThanks checks if nothanks.flag exists
-func IsNoThanks() bool {
-	flagPath := filepath.Join(GetAppDataDir(), "nothanks.flag")
-	_, err := os.Stat(flagPath)
-	return err == nil
+// IsNoThanks checks if the user opted out
+func (s State) IsNoThanks() bool {
+	r, err := s.Record()
+	return err == nil && r.NoThanksAt != nil
 }
 
-// ShouldRemindLater checks if remind.txt exists and if current time is before the reminder date
+// ShouldRemindLater checks if current time is before the Remind Me Later date
 // Returns true if we should skip showing the survey (still within reminder window)
-func ShouldRemindLater() (bool, error) {
-	remindPath := filepath.Join(GetAppDataDir(), "remind.txt")
-
-	data, err := os.ReadFile(remindPath)
+func (s State) ShouldRemindLater() (bool, error) {
+	r, err := s.Record()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil // file doesn't exist, show survey
-		}
 		return false, err
 	}
+	return r.RemindAt != nil && time.Now().Before(*r.RemindAt), nil
+}
 
-	// Parse the stored date
-	remindDate, err := time.Parse(time.RFC3339, string(data))
-	if err != nil {
-		// Invalid date format, ignore and show survey
-		return false, nil
-	}
+// ShouldShowSurvey checks all conditions and returns true if survey should be shown
+func (s State) ShouldShowSurvey() (bool, error) {
+	d := s.Evaluate(time.Now())
+	return d.Show, d.Err
+}
 
-	// If current time is before remind date, skip survey
-	if time.Now().Before(remindDate) {
-		return true, nil
-	}
+// MarkSurveyDone records the survey as answered. A pending reminder is
+// dropped and the prompt counters start over.
+func (s State) MarkSurveyDone() error {
+	return s.update(func(r *Record) {
+		now := time.Now()
+		r.DoneAt = &now
+		r.RemindAt = nil
+		r.Counters = Counters{LastPrompt: r.LastPrompt}
+	})
+}
 
-	// Reminder period has passed, show survey
-	return false, nil
+// MarkNoThanks records that the user opted out
+func (s State) MarkNoThanks() error {
+	return s.update(func(r *Record) {
+		now := time.Now()
+		r.NoThanksAt = &now
+		r.Ignored = 0
+	})
 }
 
-// ShouldShowSurvey checks all conditions and returns true if survey should be shown
-func ShouldShowSurvey() (bool, error) {
-	// Check done.flag
-	if IsSurveyDone() {
-		return false, nil
-	}
+// MarkRemindLater records a reminder date.
+// Default production behavior is a 7-day reminder; see RemindLater.
+func (s State) MarkRemindLater() error {
+	_, err := s.RemindLater()
+	return err
+}
 
-	// Check nothanks.flag
-	if IsNoThanks() {
-		return false, nil
-	}
+// RemindLater snoozes the survey for the next step of the policy's
+// snooze_days (RemindDuration without one) and counts the snooze.
+// TEST_REMIND_MINUTES overrides the duration for quick testing.
+func (s State) RemindLater() (Snooze, error) {
+	var snooze Snooze
+	err := s.update(func(r *Record) {
+		snooze = Snooze{Number: r.Snoozes + 1, Duration: s.Policy.SnoozeDuration(r.Snoozes + 1)}
+		// Allow a runtime override (TEST_REMIND_MINUTES) for quick testing.
+		if m := os.Getenv("TEST_REMIND_MINUTES"); m != "" {
+			if minutes, err := time.ParseDuration(m + "m"); err == nil {
+				snooze.Duration = minutes
+			}
+		}
+		snooze.Until = time.Now().Add(snooze.Duration)
+		snooze.Last = s.Policy.MaxConsecutiveSnoozes > 0 && snooze.Number >= s.Policy.MaxConsecutiveSnoozes
 
-	// Check remind.txt
-	shouldSkip, err := ShouldRemindLater()
+		r.RemindAt = &snooze.Until
+		r.Ignored = 0
+		r.Snoozes = snooze.Number
+	})
 	if err != nil {
-		return false, err
-	}
-	if shouldSkip {
-		return false, nil
+		return Snooze{}, err
 	}
-
-	// All checks passed, show survey
-	return true, nil
+	return snooze, nil
 }
 
-// MarkSurveyDone creates done.flag to indicate survey completion
-func MarkSurveyDone() error {
-	if err := ensureAppDataDir(); err != nil {
-		return err
-	}
-
-	flagPath := filepath.Join(GetAppDataDir(), "done.flag")
-	timestamp := time.Now().Format(time.RFC3339)
-	return os.WriteFile(flagPath, []byte(timestamp), 0644)
+// Reset clears the state's choices and prompt counters
+func (s State) Reset() error {
+	return updateStore(func(st *Store) {
+		if s.Campaign == "" {
+			st.Survey = Record{}
+		} else {
+			delete(st.Campaigns, s.Campaign)
+		}
+	})
 }
 
-// MarkNoThanks creates nothanks.flag to indicate user opted out
-func MarkNoThanks() error {
-	if err := ensureAppDataDir(); err != nil {
-		return err
+// Status returns a human-readable status for debugging: the decision with
+// its reason, and the machine policy if there is one
+func (s State) Status() string {
+	status := s.Evaluate(time.Now()).String()
+	if machine := MachinePolicyStatus(); machine != "" {
+		status += " (" + machine + ")"
 	}
-
-	flagPath := filepath.Join(GetAppDataDir(), "nothanks.flag")
-	timestamp := time.Now().Format(time.RFC3339)
-	return os.WriteFile(flagPath, []byte(timestamp), 0644)
+	return status
 }
 
-// MarkRemindLater creates/updates remind.txt with a reminder date.
-// Default production behavior is a 7-day reminder. For quick testing
-// we temporarily set this to 4 minutes.
-func MarkRemindLater() error {
-	if err := ensureAppDataDir(); err != nil {
+// update applies change to the state's record in the store, after the
+// machine policy's reset_at
+func (s State) update(change func(*Record)) error {
+	machine, err := LoadMachinePolicy()
+	if err != nil {
 		return err
 	}
+	return updateStore(func(st *Store) {
+		r := st.Record(s.Campaign)
+		*r = machine.current(*r, time.Now())
+		change(r)
+	})
+}
 
-	remindPath := filepath.Join(GetAppDataDir(), "remind.txt")
-	// Allow a runtime override (TEST_REMIND_MINUTES) for quick testing.
-	duration := RemindDuration
-	if m := os.Getenv("TEST_REMIND_MINUTES"); m != "" {
-		if minutes, err := time.ParseDuration(m + "m"); err == nil {
-			duration = minutes
-		}
-	}
-	remindDate := time.Now().Add(duration)
-	remindDateStr := remindDate.Format(time.RFC3339)
+// IsSurveyDone checks if the survey was answered
+func IsSurveyDone() bool { return legacy.IsSurveyDone() }
 
-	return os.WriteFile(remindPath, []byte(remindDateStr), 0644)
-}
+// IsNoThanks checks if the user opted out
+func IsNoThanks() bool { return legacy.IsNoThanks() }
 
-// ResetAll removes all flags/settings (useful for testing or reset functionality)
-func ResetAll() error {
-	dir := GetAppDataDir()
+// ShouldRemindLater checks if current time is before the Remind Me Later date
+func ShouldRemindLater() (bool, error) { return legacy.ShouldRemindLater() }
 
-	files := []string{
-		filepath.Join(dir, "done.flag"),
-		filepath.Join(dir, "nothanks.flag"),
-		filepath.Join(dir, "remind.txt"),
-	}
+// ShouldShowSurvey checks all conditions and returns true if survey should be shown
+func ShouldShowSurvey() (bool, error) { return legacy.ShouldShowSurvey() }
 
-	var lastErr error
-	for _, f := range files {
-		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
-			lastErr = err
-		}
-	}
+// MarkSurveyDone records the survey as answered
+func MarkSurveyDone() error { return legacy.MarkSurveyDone() }
 
-	return lastErr
-}
+// MarkNoThanks records that the user opted out
+func MarkNoThanks() error { return legacy.MarkNoThanks() }
 
-// GetStatus returns a human-readable status for debugging
-func GetStatus() string {
-	if IsSurveyDone() {
-		return "Survey completed"
-	}
-	if IsNoThanks() {
-		return "User opted out (No Thanks)"
-	}
+// MarkRemindLater records a reminder date
+func MarkRemindLater() error { return legacy.MarkRemindLater() }
 
-	shouldSkip, err := ShouldRemindLater()
+// ResetAll clears the state of every campaign (useful for testing or reset
+// functionality). The store is emptied rather than removed, so the files of
+// earlier versions are not imported again.
+func ResetAll() error {
+	unlock, err := appdata.LockFile(lockPath())
 	if err != nil {
-		return fmt.Sprintf("Error checking reminder: %v", err)
-	}
-	if shouldSkip {
-		remindPath := filepath.Join(GetAppDataDir(), "remind.txt")
-		data, _ := os.ReadFile(remindPath)
-		return fmt.Sprintf("Remind me later (until %s)", string(data))
+		return err
 	}
+	defer unlock()
 
-	return "Survey should be shown"
+	// A damaged store is replaced as well
+	var empty Store
+	if st, err := loadStore(); err == nil {
+		empty.LastLogon = st.LastLogon // not a choice of the user
+	}
+	return empty.save()
 }
+
+// GetStatus returns a human-readable status for debugging
+func GetStatus() string { return legacy.Status() }
@@ -1,13 +1,12 @@
 package startup
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 )
 
 func TestMarkAndCheckDone(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
 	// Clean up before test
 	defer ResetAll()
 	// Ensure a clean slate before assertions to avoid noise from local machine state
@@ -30,14 +29,18 @@ func TestMarkAndCheckDone(t *testing.T) {
 		t.Error("Survey should be marked as done")
 	}
 
-	// Verify file exists
-	flagPath := filepath.Join(GetAppDataDir(), "done.flag")
-	if _, err := os.Stat(flagPath); os.IsNotExist(err) {
-		t.Error("done.flag file should exist")
+	// Verify the store has it
+	st, err := LoadStore()
+	if err != nil {
+		t.Fatalf("Failed to load state store: %v", err)
+	}
+	if st.Survey.DoneAt == nil {
+		t.Error("state.json should record the survey as done")
 	}
 }
 
 func TestMarkAndCheckNoThanks(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
 	defer ResetAll()
 	if err := ResetAll(); err != nil {
 		t.Fatalf("Failed to reset state before test: %v", err)
@@ -57,6 +60,7 @@ func TestMarkAndCheckNoThanks(t *testing.T) {
 }
 
 func TestRemindLater(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
 	defer ResetAll()
 	if err := ResetAll(); err != nil {
 		t.Fatalf("Failed to reset state before test: %v", err)
@@ -86,16 +90,14 @@ func TestRemindLater(t *testing.T) {
 	}
 
 	// Verify the date is approximately 7 days in future
-	remindPath := filepath.Join(GetAppDataDir(), "remind.txt")
-	data, err := os.ReadFile(remindPath)
+	st, err := LoadStore()
 	if err != nil {
-		t.Fatalf("Failed to read remind.txt: %v", err)
+		t.Fatalf("Failed to load state store: %v", err)
 	}
-
-	remindDate, err := time.Parse(time.RFC3339, string(data))
-	if err != nil {
-		t.Fatalf("Invalid date in remind.txt: %v", err)
+	if st.Survey.RemindAt == nil {
+		t.Fatal("state.json should record the reminder date")
 	}
+	remindDate := *st.Survey.RemindAt
 
 	expectedDate := time.Now().Add(7 * 24 * time.Hour)
 	diff := remindDate.Sub(expectedDate)
@@ -105,6 +107,7 @@ func TestRemindLater(t *testing.T) {
 }
 
 func TestShouldShowSurvey(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
 	defer ResetAll()
 	if err := ResetAll(); err != nil {
 		t.Fatalf("Failed to reset state before test: %v", err)
@@ -144,26 +147,63 @@ func TestShouldShowSurvey(t *testing.T) {
 }
 
 func TestGetStatus(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
 	defer ResetAll()
 	if err := ResetAll(); err != nil {
 		t.Fatalf("Failed to reset state before test: %v", err)
 	}
 
 	status := GetStatus()
-	if status != "Survey should be shown" {
-		t.Errorf("Expected 'Survey should be shown', got: %s", status)
+	if status != "Survey should be shown [show]" {
+		t.Errorf("Expected 'Survey should be shown [show]', got: %s", status)
 	}
 
 	MarkSurveyDone()
 	status = GetStatus()
-	if status != "Survey completed" {
-		t.Errorf("Expected 'Survey completed', got: %s", status)
+	if status != "Survey completed [done]" {
+		t.Errorf("Expected 'Survey completed [done]', got: %s", status)
 	}
 
 	ResetAll()
 	MarkNoThanks()
 	status = GetStatus()
-	if status != "User opted out (No Thanks)" {
-		t.Errorf("Expected 'User opted out (No Thanks)', got: %s", status)
+	if status != "User opted out (No Thanks) [opted_out]" {
+		t.Errorf("Expected 'User opted out (No Thanks) [opted_out]', got: %s", status)
+	}
+}
+
+func TestCampaignState(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	// Finishing the survey before campaigns existed does not skip a campaign
+	if err := MarkSurveyDone(); err != nil {
+		t.Fatalf("Failed to mark survey as done: %v", err)
+	}
+	q2, q3 := For("q2-2026"), For("q3-2026")
+	if show, _ := q2.ShouldShowSurvey(); !show {
+		t.Error("A campaign should be shown to users who answered before campaigns")
+	}
+
+	if err := q2.MarkSurveyDone(); err != nil {
+		t.Fatalf("Failed to mark campaign as done: %v", err)
+	}
+	if err := q3.MarkNoThanks(); err != nil {
+		t.Fatalf("Failed to mark campaign NoThanks: %v", err)
+	}
+	if show, _ := q2.ShouldShowSurvey(); show {
+		t.Error("A finished campaign should not be shown again")
+	}
+	if show, _ := For("q4-2026").ShouldShowSurvey(); !show {
+		t.Error("A new campaign should be shown after earlier ones were finished or declined")
+	}
+	if status := q3.Status(); status != "User opted out (No Thanks) [opted_out]" {
+		t.Errorf("Unexpected status: %s", status)
+	}
+
+	if err := ResetAll(); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if q2.IsSurveyDone() || q3.IsNoThanks() || IsSurveyDone() {
+		t.Error("ResetAll should clear the flags of every campaign")
 	}
 }
@@ -0,0 +1,263 @@
+package startup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"customer-survey/pkg/appdata"
+)
+
+// StateFile is the per-user state store in appdata.Dir. It is the only
+// place eligibility is read from; the flag files and prompt_settings.json
+// of earlier versions are imported once, when it does not exist yet.
+const StateFile = "state.json"
+
+// StateVersion is the format version of StateFile this build reads and writes
+const StateVersion = 1
+
+// Store is the content of StateFile
+type Store struct {
+	Version    int                `json:"version"`
+	Survey     Record             `json:"survey"`              // the state without a campaign
+	Campaigns  map[string]*Record `json:"campaigns,omitempty"` // by campaign id
+	LastLogon  *time.Time         `json:"last_logon,omitempty"`
+	MigratedAt *time.Time         `json:"migrated_at,omitempty"` // when the files of earlier versions were imported
+}
+
+// Record is the state of the survey without a campaign, or of one campaign
+type Record struct {
+	DoneAt     *time.Time `json:"done_at,omitempty"`
+	NoThanksAt *time.Time `json:"no_thanks_at,omitempty"`
+	RemindAt   *time.Time `json:"remind_at,omitempty"` // Remind Me Later: not shown before this time
+	ResetFor   *time.Time `json:"reset_for,omitempty"` // the machine policy's reset_at this record was cleared for
+	Counters
+}
+
+// Counters track the prompts shown since the survey was last answered
+type Counters struct {
+	Prompts    int        `json:"prompts,omitempty"` // prompts shown since the last answer
+	Ignored    int        `json:"ignored,omitempty"` // prompts in a row closed without any choice
+	Snoozes    int        `json:"snoozes,omitempty"` // Remind Me Later in a row
+	LastPrompt *time.Time `json:"last_prompt,omitempty"`
+}
+
+// StatePath returns the path of the state store
+func StatePath() string {
+	return filepath.Join(appdata.Dir(), StateFile)
+}
+
+// lockPath is the advisory lock file held around every access to the
+// store, so sessions of the same user (e.g. on RDS) take turns
+func lockPath() string {
+	return StatePath() + ".lock"
+}
+
+// LoadStore reads the state store. The first time, when there is none, it
+// is built from the files of earlier versions and saved.
+func LoadStore() (*Store, error) {
+	unlock, err := appdata.LockFile(lockPath())
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return loadStore()
+}
+
+// Save writes the state store
+func (st *Store) Save() error {
+	unlock, err := appdata.LockFile(lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return st.save()
+}
+
+func loadStore() (*Store, error) {
+	data, err := os.ReadFile(StatePath())
+	if os.IsNotExist(err) {
+		st := migrate()
+		return st, st.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st Store
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("%s: %v", StatePath(), err)
+	}
+	if st.Version > StateVersion {
+		return nil, fmt.Errorf("%s: version %d is newer than this build supports (%d)", StatePath(), st.Version, StateVersion)
+	}
+	st.Version = StateVersion
+	return &st, nil
+}
+
+func (st *Store) save() error {
+	if err := os.MkdirAll(appdata.Dir(), 0755); err != nil {
+		return err
+	}
+	st.Version = StateVersion
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return appdata.WriteFileAtomic(StatePath(), data, 0644)
+}
+
+// Record returns the record of a campaign ("" for none), creating it
+func (st *Store) Record(campaign string) *Record {
+	if campaign == "" {
+		return &st.Survey
+	}
+	if st.Campaigns == nil {
+		st.Campaigns = map[string]*Record{}
+	}
+	r, ok := st.Campaigns[campaign]
+	if !ok {
+		r = &Record{}
+		st.Campaigns[campaign] = r
+	}
+	return r
+}
+
+// updateStore loads the store, applies change and saves it, holding the
+// lock throughout so no other update is lost in between
+func updateStore(change func(*Store)) error {
+	unlock, err := appdata.LockFile(lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := loadStore()
+	if err != nil {
+		return err
+	}
+	change(st)
+	return st.save()
+}
+
+// Files of earlier versions, imported by migrate
+const (
+	legacyCampaignsDir = "campaigns" // one folder of flag files per campaign
+	legacyLogonFile    = "logon.txt"
+	legacyCountersFile = "cadence.json"
+)
+
+// migrate builds the store from the flag files in the AppData folder and
+// its campaign folders, and from %LOCALAPPDATA%\.ace-survey\prompt_settings.json
+// written by the older prompt settings code. The old files are left in
+// place for a rollback but are not read again.
+func migrate() *Store {
+	now := time.Now()
+	st := &Store{Version: StateVersion, MigratedAt: &now}
+	dir := appdata.Dir()
+
+	imported := importFlags(dir, &st.Survey)
+	if data, err := os.ReadFile(filepath.Join(dir, legacyLogonFile)); err == nil {
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
+			st.LastLogon = &t
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(dir, legacyCampaignsDir)); err == nil {
+		for _, e := range entries {
+			var r Record
+			if e.IsDir() && importFlags(filepath.Join(dir, legacyCampaignsDir, e.Name()), &r) {
+				*st.Record(e.Name()) = r
+				imported = true
+			}
+		}
+	}
+	if importPromptSettings(&st.Survey) {
+		imported = true
+	}
+	if !imported {
+		st.MigratedAt = nil
+	}
+	return st
+}
+
+// importFlags reads done.flag, nothanks.flag, remind.txt and cadence.json
+// from dir into r and reports whether any existed
+func importFlags(dir string, r *Record) bool {
+	found := false
+	flagTime := func(name string) *time.Time {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		found = true
+		t := info.ModTime()
+		if data, err := os.ReadFile(path); err == nil {
+			if parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data))); err == nil {
+				t = parsed
+			}
+		}
+		return &t
+	}
+	r.DoneAt = flagTime("done.flag")
+	r.NoThanksAt = flagTime("nothanks.flag")
+	if t := flagTime("remind.txt"); t != nil && t.After(time.Now()) {
+		r.RemindAt = t
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, legacyCountersFile)); err == nil {
+		found = true
+		json.Unmarshal(data, &r.Counters)
+		if r.LastPrompt != nil && r.LastPrompt.IsZero() {
+			r.LastPrompt = nil
+		}
+	}
+	return found
+}
+
+// legacyPromptSettings is prompt_settings.json of the older prompt code
+type legacyPromptSettings struct {
+	NoThanks               bool      `json:"no_thanks"`
+	RemindMeLaterTimestamp time.Time `json:"remind_me_later_timestamp"`
+	LastShownTimestamp     time.Time `json:"last_shown_timestamp"`
+	RemindMeLaterDays      int       `json:"remind_me_later_days"`
+}
+
+// importPromptSettings merges prompt_settings.json into r, keeping the
+// choice that keeps the survey away longer where both stores have one
+func importPromptSettings(r *Record) bool {
+	dir := os.Getenv("LOCALAPPDATA")
+	if dir == "" {
+		dir = os.Getenv("APPDATA")
+	}
+	if dir == "" {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".ace-survey", "prompt_settings.json"))
+	if err != nil {
+		return false
+	}
+	var ps legacyPromptSettings
+	if err := json.Unmarshal(data, &ps); err != nil {
+		return false
+	}
+	if ps.NoThanks && r.NoThanksAt == nil {
+		t := ps.LastShownTimestamp
+		if t.IsZero() {
+			t = time.Now()
+		}
+		r.NoThanksAt = &t
+	}
+	if !ps.RemindMeLaterTimestamp.IsZero() {
+		days := ps.RemindMeLaterDays
+		if days == 0 {
+			days = 7
+		}
+		until := ps.RemindMeLaterTimestamp.AddDate(0, 0, days)
+		if until.After(time.Now()) && (r.RemindAt == nil || until.After(*r.RemindAt)) {
+			r.RemindAt = &until
+		}
+	}
+	return true
+}
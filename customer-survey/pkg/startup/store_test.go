@@ -0,0 +1,183 @@
+package startup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/appdata"
+)
+
+func TestMigrateLegacyFiles(t *testing.T) {
+	appData := t.TempDir()
+	t.Setenv("APPDATA", appData)
+	t.Setenv("LOCALAPPDATA", "")
+
+	dir := appdata.Dir()
+	remind := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(filepath.Join(dir, "done.flag"), "2026-01-05T10:00:00Z")
+	write(filepath.Join(dir, "logon.txt"), "2026-03-01T08:00:00Z")
+	write(filepath.Join(dir, "campaigns", "q1", "nothanks.flag"), "2026-02-01T09:00:00Z")
+	write(filepath.Join(dir, "campaigns", "q2", "remind.txt"), remind.Format(time.RFC3339))
+	write(filepath.Join(dir, "campaigns", "q2", "cadence.json"), `{"prompts": 3, "ignored": 1, "snoozes": 1}`)
+	// The older prompt settings falls back to APPDATA without LOCALAPPDATA
+	write(filepath.Join(appData, ".ace-survey", "prompt_settings.json"),
+		`{"no_thanks": true, "last_shown_timestamp": "2026-01-04T10:00:00Z"}`)
+
+	st, err := LoadStore()
+	if err != nil {
+		t.Fatalf("Failed to load state store: %v", err)
+	}
+	if st.MigratedAt == nil || st.Version != StateVersion {
+		t.Errorf("Expected a migrated store of version %d, got %+v", StateVersion, st)
+	}
+	if st.Survey.DoneAt == nil || st.Survey.NoThanksAt == nil {
+		t.Errorf("Expected done.flag and prompt_settings.json to be merged, got %+v", st.Survey)
+	}
+	if st.LastLogon == nil || st.LastLogon.Format(time.RFC3339) != "2026-03-01T08:00:00Z" {
+		t.Errorf("Unexpected last logon: %v", st.LastLogon)
+	}
+	if q1 := st.Campaigns["q1"]; q1 == nil || q1.NoThanksAt == nil {
+		t.Errorf("Campaign q1 should be opted out: %+v", q1)
+	}
+	if q2 := st.Campaigns["q2"]; q2 == nil || q2.RemindAt == nil || !q2.RemindAt.Equal(remind) || q2.Prompts != 3 || q2.Snoozes != 1 {
+		t.Errorf("Campaign q2 should be snoozed with its counters: %+v", q2)
+	}
+	if _, err := os.Stat(StatePath()); err != nil {
+		t.Errorf("The migrated store should be saved: %v", err)
+	}
+
+	// The old files are imported once; a choice made since wins
+	if err := For("q1").Reset(); err != nil {
+		t.Fatalf("Failed to reset campaign: %v", err)
+	}
+	if show, _ := For("q1").ShouldShowSurvey(); !show {
+		t.Error("nothanks.flag of campaign q1 should not be read again")
+	}
+	if err := ResetAll(); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if snoozed, _ := For("q2").ShouldRemindLater(); snoozed || IsSurveyDone() {
+		t.Error("The old files should not be imported again after a reset")
+	}
+}
+
+func TestStoreVersion(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	if err := os.MkdirAll(appdata.Dir(), 0755); err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(StatePath(), []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatalf("Failed to write state store: %v", err)
+	}
+	if _, err := LoadStore(); err == nil {
+		t.Error("A store of a newer version should not be read")
+	}
+	if d := legacy.Evaluate(time.Now()); d.Show || d.Reason != ReasonStateError {
+		t.Errorf("Expected %s for an unreadable store, got %+v", ReasonStateError, d)
+	}
+}
+
+// hammer counts prompts and snoozes the survey n times
+func hammer(n int) error {
+	s := For("hammer")
+	for i := 0; i < n; i++ {
+		if err := s.RecordPrompt(); err != nil {
+			return err
+		}
+		if _, err := s.RemindLater(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkHammered verifies that no update was lost and the store is intact
+func checkHammered(t *testing.T, want int) {
+	t.Helper()
+	data, err := os.ReadFile(StatePath())
+	if err != nil {
+		t.Fatalf("Failed to read state store: %v", err)
+	}
+	var st Store
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("State store is corrupt: %v\n%s", err, data)
+	}
+	if r := st.Campaigns["hammer"]; r == nil || r.Prompts != want || r.Snoozes != want || r.RemindAt == nil {
+		t.Errorf("Expected %d prompts and snoozes, got %+v", want, r)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(appdata.Dir(), "*.tmp")); len(tmp) > 0 {
+		t.Errorf("Temporary files left behind: %v", tmp)
+	}
+}
+
+func TestStoreConcurrentUpdates(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	const workers, updates = 8, 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- hammer(updates)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to update state store: %v", err)
+		}
+	}
+	checkHammered(t, workers*updates)
+}
+
+func TestStoreConcurrentProcesses(t *testing.T) {
+	const workers, updates = 4, 25
+	if n := os.Getenv("STARTUP_HAMMER"); n != "" {
+		// Child process: APPDATA is set by the parent
+		var count int
+		fmt.Sscan(n, &count)
+		if err := hammer(count); err != nil {
+			t.Fatalf("Failed to update state store: %v", err)
+		}
+		return
+	}
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+	t.Setenv("APPDATA", t.TempDir())
+
+	cmds := make([]*exec.Cmd, workers)
+	output := make([]bytes.Buffer, workers)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestStoreConcurrentProcesses$")
+		cmds[i].Env = append(os.Environ(), fmt.Sprintf("STARTUP_HAMMER=%d", updates))
+		cmds[i].Stdout, cmds[i].Stderr = &output[i], &output[i]
+		if err := cmds[i].Start(); err != nil {
+			t.Fatalf("Failed to start process: %v", err)
+		}
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Process failed: %v\n%s", err, output[i].String())
+		}
+	}
+	checkHammered(t, workers*updates)
+}
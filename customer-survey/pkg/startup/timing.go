@@ -0,0 +1,164 @@
+package startup
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timing is the "prompt_timing" section of the configuration: when, within
+// a logon, the prompt may appear. The zero Timing shows it right away.
+type Timing struct {
+	LogonDelaySeconds      int      `json:"logon_delay_seconds,omitempty"`       // wait this long after logon
+	Weekdays               []string `json:"weekdays,omitempty"`                  // "mon" ... "sun", local time; empty for every day
+	From                   string   `json:"from,omitempty"`                      // "HH:MM" local time, empty for midnight
+	Until                  string   `json:"until,omitempty"`                     // "HH:MM" local time (exclusive), empty for midnight
+	SkipAfterPasswordReset bool     `json:"skip_after_password_reset,omitempty"` // no prompt on the first logon after a password change
+}
+
+// More reasons of a Decision, from Timing.Check
+const (
+	ReasonLogonDelay    = "logon_delay"    // wait until Until, then check again
+	ReasonQuietHours    = "quiet_hours"    // outside the allowed weekdays or hours
+	ReasonPasswordReset = "password_reset" // first logon after a password change
+)
+
+// recentPasswordChange is how old a password change may be to count as
+// part of this logon when no earlier logon is on record
+const recentPasswordChange = time.Hour
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Logon describes the logon the survey runs in
+type Logon struct {
+	At            time.Time // when the exe started; it is launched from the Startup folder at logon
+	Previous      time.Time // the logon before, zero if none is on record
+	PasswordSetAt time.Time // when the user's password was last set, zero if unknown
+}
+
+// Validate reports the first malformed setting
+func (t Timing) Validate() error {
+	if t.LogonDelaySeconds < 0 {
+		return fmt.Errorf("logon_delay_seconds: must not be negative, got %d", t.LogonDelaySeconds)
+	}
+	for i, day := range t.Weekdays {
+		if _, ok := weekday(day); !ok {
+			return fmt.Errorf("weekdays[%d]: unknown day %q (expected one of %s)", i, day, strings.Join(weekdayNames, ", "))
+		}
+	}
+	if _, err := clock(t.From); err != nil {
+		return fmt.Errorf("from: %v", err)
+	}
+	if _, err := clock(t.Until); err != nil {
+		return fmt.Errorf("until: %v", err)
+	}
+	return nil
+}
+
+// Check decides whether the prompt may appear at now. With
+// ReasonLogonDelay the caller waits until Decision.Until and checks again.
+func (t Timing) Check(now time.Time, logon Logon) Decision {
+	if t.SkipAfterPasswordReset && passwordReset(logon) {
+		return Decision{Reason: ReasonPasswordReset,
+			Message: fmt.Sprintf("First logon after a password change (%s)", logon.PasswordSetAt.Format(time.RFC3339))}
+	}
+
+	if delay := time.Duration(t.LogonDelaySeconds) * time.Second; delay > 0 && !logon.At.IsZero() {
+		if ready := logon.At.Add(delay); now.Before(ready) {
+			return Decision{Reason: ReasonLogonDelay, Until: ready,
+				Message: fmt.Sprintf("Waiting %s after logon (until %s)", delay, ready.Format(time.RFC3339))}
+		}
+	}
+
+	if !t.allowed(now) {
+		return Decision{Reason: ReasonQuietHours,
+			Message: fmt.Sprintf("Outside the allowed prompt times (%s)", t.describe())}
+	}
+	return Decision{Show: true, Reason: ReasonShow, Message: "Survey should be shown"}
+}
+
+// allowed reports whether now falls on an allowed weekday and hour
+func (t Timing) allowed(now time.Time) bool {
+	now = now.Local()
+	if len(t.Weekdays) > 0 {
+		ok := false
+		for _, day := range t.Weekdays {
+			if d, _ := weekday(day); d == now.Weekday() {
+				ok = true
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	from, _ := clock(t.From)
+	until, _ := clock(t.Until)
+	if from == until {
+		return true
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if from < until {
+		return minute >= from && minute < until
+	}
+	// The window wraps around midnight, e.g. 22:00-06:00
+	return minute >= from || minute < until
+}
+
+func (t Timing) describe() string {
+	days := "every day"
+	if len(t.Weekdays) > 0 {
+		days = strings.Join(t.Weekdays, ", ")
+	}
+	if t.From == "" && t.Until == "" {
+		return days
+	}
+	return fmt.Sprintf("%s, %s-%s", days, t.From, t.Until)
+}
+
+// passwordReset reports whether the password was set since the previous
+// logon, or, without one on record, shortly before this one
+func passwordReset(logon Logon) bool {
+	if logon.PasswordSetAt.IsZero() {
+		return false
+	}
+	if !logon.Previous.IsZero() {
+		return logon.PasswordSetAt.After(logon.Previous)
+	}
+	return logon.At.Sub(logon.PasswordSetAt) < recentPasswordChange
+}
+
+// RecordLogon returns the logon starting at start, with the previous logon
+// and the password's age, and records it for the next one
+func RecordLogon(start time.Time) (Logon, error) {
+	logon := Logon{At: start, PasswordSetAt: passwordLastSet()}
+	err := updateStore(func(st *Store) {
+		if st.LastLogon != nil {
+			logon.Previous = *st.LastLogon
+		}
+		st.LastLogon = &start
+	})
+	return logon, err
+}
+
+// weekday reads "mon" or "monday", in any case
+func weekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, day := range weekdayNames {
+		if name == day || name == strings.ToLower(time.Weekday(i).String()) {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// clock parses "HH:MM" into minutes after midnight; "" is midnight
+func clock(s string) (int, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time of day (HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,83 @@
+package startup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimingWindow(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("Mon 2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatalf("Bad test time %q: %v", s, err)
+		}
+		return tm
+	}
+	office := Timing{Weekdays: []string{"mon", "Tuesday", "wed", "thu", "fri"}, From: "09:30", Until: "16:00"}
+	night := Timing{From: "22:00", Until: "06:00"}
+	for _, tc := range []struct {
+		timing Timing
+		now    string
+		want   string
+	}{
+		{office, "Wed 2026-10-14 10:00", ReasonShow},
+		{office, "Tue 2026-10-13 09:29", ReasonQuietHours},
+		{office, "Tue 2026-10-13 16:00", ReasonQuietHours},
+		{office, "Sat 2026-10-17 10:00", ReasonQuietHours},
+		{night, "Sat 2026-10-17 23:30", ReasonShow},
+		{night, "Sun 2026-10-18 05:59", ReasonShow},
+		{night, "Sun 2026-10-18 12:00", ReasonQuietHours},
+		{Timing{}, "Sun 2026-10-18 03:00", ReasonShow},
+	} {
+		if d := tc.timing.Check(at(tc.now), Logon{}); d.Reason != tc.want || d.Show != (tc.want == ReasonShow) {
+			t.Errorf("%+v at %s: got %s, want %s", tc.timing, tc.now, d, tc.want)
+		}
+	}
+}
+
+func TestTimingLogon(t *testing.T) {
+	logonAt := time.Date(2026, 10, 14, 8, 0, 0, 0, time.Local)
+	timing := Timing{LogonDelaySeconds: 300, SkipAfterPasswordReset: true}
+
+	d := timing.Check(logonAt.Add(time.Minute), Logon{At: logonAt})
+	if d.Reason != ReasonLogonDelay || !d.Until.Equal(logonAt.Add(5*time.Minute)) {
+		t.Errorf("Expected to wait until 5 minutes after logon, got %+v", d)
+	}
+	if d := timing.Check(logonAt.Add(5*time.Minute), Logon{At: logonAt}); !d.Show {
+		t.Errorf("Expected the prompt after the delay, got %s", d)
+	}
+
+	previous := logonAt.AddDate(0, 0, -1)
+	for name, tc := range map[string]struct {
+		logon Logon
+		skip  bool
+	}{
+		"changed since last logon":    {Logon{At: logonAt, Previous: previous, PasswordSetAt: logonAt.Add(-time.Hour)}, true},
+		"changed before last logon":   {Logon{At: logonAt, Previous: previous, PasswordSetAt: previous.Add(-time.Hour)}, false},
+		"first run, just changed":     {Logon{At: logonAt, PasswordSetAt: logonAt.Add(-2 * time.Minute)}, true},
+		"first run, changed long ago": {Logon{At: logonAt, PasswordSetAt: logonAt.AddDate(0, -1, 0)}, false},
+		"password age unknown":        {Logon{At: logonAt, Previous: previous}, false},
+	} {
+		d := timing.Check(logonAt.Add(time.Hour), tc.logon)
+		if (d.Reason == ReasonPasswordReset) != tc.skip {
+			t.Errorf("%s: got %s", name, d)
+		}
+	}
+}
+
+func TestRecordLogon(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	first := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+
+	logon, err := RecordLogon(first)
+	if err != nil {
+		t.Fatalf("Failed to record logon: %v", err)
+	}
+	if !logon.Previous.IsZero() {
+		t.Errorf("The first logon should have no previous one, got %s", logon.Previous)
+	}
+	logon, _ = RecordLogon(time.Now())
+	if !logon.Previous.Equal(first) {
+		t.Errorf("Expected the previous logon %s, got %s", first, logon.Previous)
+	}
+}
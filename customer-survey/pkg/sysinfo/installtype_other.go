@@ -0,0 +1,8 @@
+//go:build !windows
+
+package sysinfo
+
+// installationType is only known on Windows
+func installationType() string {
+	return ""
+}
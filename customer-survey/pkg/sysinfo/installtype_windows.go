@@ -0,0 +1,18 @@
+package sysinfo
+
+import "golang.org/x/sys/windows/registry"
+
+// installationType reads InstallationType ("Client", "Server" or "Server
+// Core") from the registry
+func installationType() string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+	value, _, err := k.GetStringValue("InstallationType")
+	if err != nil {
+		return ""
+	}
+	return value
+}
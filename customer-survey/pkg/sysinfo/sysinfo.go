@@ -0,0 +1,74 @@
+// Package sysinfo captures attributes of the machine the survey runs on,
+// for survey display conditions
+package sysinfo
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Attribute names usable in a "system" display condition
+const (
+	MachineType      = "machine_type"      // "server" or "workstation"
+	InstallationType = "installation_type" // Windows InstallationType, lower case: "client", "server", "server core"
+	OS               = "os"                // "windows", "linux", ...
+)
+
+// Names lists every attribute Collect captures
+var Names = []string{MachineType, InstallationType, OS}
+
+// Machine types
+const (
+	Server      = "server"
+	Workstation = "workstation"
+)
+
+// Attributes maps attribute names to values; unknown values are left out
+type Attributes map[string]string
+
+var (
+	once      sync.Once
+	collected Attributes
+)
+
+// Collect returns the attributes of this machine. They are read once per
+// process.
+func Collect() Attributes {
+	once.Do(func() {
+		collected = FromInstallationType(installationType())
+		collected[OS] = runtime.GOOS
+	})
+	out := Attributes{}
+	for k, v := range collected {
+		out[k] = v
+	}
+	return out
+}
+
+// FromInstallationType derives the attributes of a Windows InstallationType
+// value ("Client", "Server", "Server Core"); an empty value yields none
+func FromInstallationType(value string) Attributes {
+	attrs := Attributes{}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return attrs
+	}
+	attrs[InstallationType] = value
+	if strings.HasPrefix(value, "server") {
+		attrs[MachineType] = Server
+	} else {
+		attrs[MachineType] = Workstation
+	}
+	return attrs
+}
+
+// Known reports whether name is an attribute Collect captures
+func Known(name string) bool {
+	for _, n := range Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
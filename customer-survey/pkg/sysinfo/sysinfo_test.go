@@ -0,0 +1,19 @@
+package sysinfo
+
+import "testing"
+
+func TestFromInstallationType(t *testing.T) {
+	for value, want := range map[string]string{
+		"Server":      Server,
+		"Server Core": Server,
+		"Client":      Workstation,
+		"":            "",
+	} {
+		if got := FromInstallationType(value)[MachineType]; got != want {
+			t.Errorf("InstallationType %q: expected machine_type %q, got %q", value, want, got)
+		}
+	}
+	if Collect()[OS] == "" {
+		t.Error("Collect should always report the OS")
+	}
+}
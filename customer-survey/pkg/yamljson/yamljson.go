@@ -0,0 +1,74 @@
+// Package yamljson re-encodes YAML documents as JSON, so configuration and
+// survey definitions written in YAML are read through the same json struct
+// tags as their JSON versions.
+package yamljson
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Convert re-encodes a YAML document whose top level is a mapping as a JSON
+// object; an empty document is an empty object. Timestamps stay the text
+// they were written as: yaml.v3 would turn an unquoted 2026-09-30 into
+// midnight UTC, which loses that a day was meant.
+func Convert(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return []byte("{}"), nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping at the top level", doc.Content[0].Line)
+	}
+	v, err := value(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// value converts one node into the values encoding/json writes
+func value(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return value(n.Content[0])
+	case yaml.AliasNode:
+		return value(n.Alias)
+	case yaml.MappingNode:
+		out := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := value(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			out[n.Content[i].Value] = v
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := value(c)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	}
+	if n.Tag == "!!timestamp" {
+		return n.Value, nil
+	}
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil, fmt.Errorf("line %d: %v", n.Line, err)
+	}
+	return v, nil
+}
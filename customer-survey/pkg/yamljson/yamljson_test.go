@@ -0,0 +1,31 @@
+package yamljson
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	for _, tc := range []struct {
+		name, yaml, json string
+	}{
+		{"empty", "", `{}`},
+		{"scalars", "a: 1\nb: 2.5\nc: true\nd: text\ne: null\n", `{"a":1,"b":2.5,"c":true,"d":"text","e":null}`},
+		{"timestamps as written", "start: 2026-07-01\nat: 2026-07-01T08:00:00+02:00\n", `{"at":"2026-07-01T08:00:00+02:00","start":"2026-07-01"}`},
+		{"nested", "list:\n  - x: 1\n  - [a, b]\n", `{"list":[{"x":1},["a","b"]]}`},
+		{"aliases", "base: &b {x: 1}\ncopy: *b\n", `{"base":{"x":1},"copy":{"x":1}}`},
+	} {
+		got, err := Convert([]byte(tc.yaml))
+		if err != nil {
+			t.Errorf("%s: failed to convert: %v", tc.name, err)
+			continue
+		}
+		if string(got) != tc.json {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.json, got)
+		}
+	}
+
+	if _, err := Convert([]byte("- a\n- b\n")); err == nil {
+		t.Error("A document that is not a mapping should be rejected")
+	}
+	if _, err := Convert([]byte("a: [1\n")); err == nil {
+		t.Error("Malformed YAML should be rejected")
+	}
+}
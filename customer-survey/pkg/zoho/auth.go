@@ -0,0 +1,293 @@
+package zoho
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"customer-survey/pkg/retry"
+)
+
+// Config holds Zoho Creator API configuration
+type Config struct {
+	AccountOwner string `json:"account_owner"`
+	AppLinkName  string `json:"app_link_name"`
+	FormLinkName string `json:"form_link_name"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	DataCenter   string `json:"data_center"` // Default: "com" (US), "eu", "in", "com.au", "jp"
+}
+
+// Auth manages OAuth tokens for Zoho Creator API
+type Auth struct {
+	config       *Config
+	accessToken  string
+	tokenExpiry  time.Time
+	mu           sync.RWMutex
+	tokenBaseURL string
+	client       *http.Client
+	cache        *TokenCache
+}
+
+// ErrRefreshTokenRevoked means Zoho rejected the refresh token (invalid_grant):
+// it was revoked, expired or belongs to another client. Nothing will be sent
+// to Creator until a new refresh token is configured.
+var ErrRefreshTokenRevoked = errors.New("zoho refresh token was rejected (invalid_grant)")
+
+// TokenResponse represents Zoho OAuth token response
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	APIServerID string `json:"api_domain"`
+	TokenType   string `json:"token_type"`
+	Error       string `json:"error"` // set instead of a token, often with HTTP 200
+}
+
+// NewAuth creates a new Zoho authentication manager. It keeps a copy of
+// config, which the caller may change or reuse.
+func NewAuth(config *Config) *Auth {
+	cfg := *config
+	if cfg.DataCenter == "" {
+		cfg.DataCenter = "com" // Default to US
+	}
+
+	return &Auth{
+		config:       &cfg,
+		tokenBaseURL: fmt.Sprintf("https://accounts.zoho.%s/oauth/v2/token", cfg.DataCenter),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		cache:        DefaultTokenCache(),
+	}
+}
+
+// SetTimeout sets the timeout of every request to Zoho
+func (z *Auth) SetTimeout(d time.Duration) {
+	z.client.Timeout = d
+}
+
+// SetTokenCache replaces the on-disk token cache; nil disables caching
+func (z *Auth) SetTokenCache(c *TokenCache) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.cache = c
+}
+
+// SecureConfigFile is the name of the optional file holding Creator
+// credentials as {"zoho": {...}}; see pkg/config for where it is looked for
+const SecureConfigFile = "zoho_secure.json"
+
+// Validate reports the first missing field needed to submit to Creator
+func (c *Config) Validate() error {
+	required := []struct{ name, value string }{
+		{"account_owner", c.AccountOwner},
+		{"app_link_name", c.AppLinkName},
+		{"form_link_name", c.FormLinkName},
+		{"client_id", c.ClientID},
+		{"client_secret", c.ClientSecret},
+		{"refresh_token", c.RefreshToken},
+	}
+	for _, f := range required {
+		if f.value == "" {
+			return fmt.Errorf("zoho creator config: %s is empty", f.name)
+		}
+	}
+	return nil
+}
+
+// GetAccessToken returns a valid access token, refreshing if necessary.
+// ctx bounds the refresh request.
+func (z *Auth) GetAccessToken(ctx context.Context) (string, error) {
+	z.mu.RLock()
+	// If token is valid and not expiring in next 5 minutes, return it
+	if z.accessToken != "" && time.Now().Add(5*time.Minute).Before(z.tokenExpiry) {
+		token := z.accessToken
+		z.mu.RUnlock()
+		return token, nil
+	}
+	z.mu.RUnlock()
+
+	// Need to refresh token
+	return z.refreshAccessToken(ctx)
+}
+
+// refreshAccessToken gets a new access token using the refresh token.
+// A token cached by an earlier launch is reused while it is still valid.
+func (z *Auth) refreshAccessToken(ctx context.Context) (string, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	// Double-check after acquiring lock
+	if z.accessToken != "" && time.Now().Add(5*time.Minute).Before(z.tokenExpiry) {
+		return z.accessToken, nil
+	}
+
+	fingerprint := z.config.fingerprint()
+	if z.cache != nil {
+		if tok := z.cache.load(fingerprint); tok != nil {
+			if tok.Revoked {
+				// Already rejected on an earlier launch; don't hammer the token endpoint
+				return "", retry.Stop(ErrRefreshTokenRevoked)
+			}
+			if time.Now().Add(5 * time.Minute).Before(tok.Expiry) {
+				z.accessToken = tok.AccessToken
+				z.tokenExpiry = tok.Expiry
+				return z.accessToken, nil
+			}
+		}
+	}
+
+	// Prepare refresh token request
+	data := url.Values{}
+	data.Set("refresh_token", z.config.RefreshToken)
+	data.Set("client_id", z.config.ClientID)
+	data.Set("client_secret", z.config.ClientSecret)
+	data.Set("grant_type", "refresh_token")
+
+	// Make request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, z.tokenBaseURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return "", retry.Permanent(fmt.Errorf("failed to create token request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := z.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	// Parse response; Zoho reports a bad grant in the body, usually with HTTP 200
+	var tokenResp TokenResponse
+	parseErr := json.Unmarshal(body, &tokenResp)
+
+	if tokenResp.Error == "invalid_grant" || tokenResp.Error == "invalid_code" {
+		log.Printf("[zoho-oauth] CONFIGURATION ERROR: the refresh token for client %s was rejected (%s). "+
+			"It has been revoked or has expired. Generate a new refresh token and update %s or ZOHO_REFRESH_TOKEN; "+
+			"submissions stay queued locally until then.", z.config.ClientID, tokenResp.Error, SecureConfigFile)
+		z.accessToken = ""
+		if z.cache != nil {
+			if err := z.cache.save(&cachedToken{Fingerprint: fingerprint, Revoked: true}); err != nil {
+				log.Printf("[zoho-oauth] Could not update token cache: %v", err)
+			}
+		}
+		return "", retry.Stop(ErrRefreshTokenRevoked)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token refresh failed (status %d): %s", resp.StatusCode, string(body))
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", parseErr)
+	}
+	if tokenResp.Error != "" || tokenResp.AccessToken == "" {
+		return "", fmt.Errorf("token refresh failed: %s", string(body))
+	}
+
+	// Update stored token
+	z.accessToken = tokenResp.AccessToken
+	z.tokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+
+	if z.cache != nil {
+		tok := &cachedToken{Fingerprint: fingerprint, AccessToken: z.accessToken, Expiry: z.tokenExpiry}
+		if err := z.cache.save(tok); err != nil {
+			log.Printf("[zoho-oauth] Could not update token cache: %v", err)
+		}
+	}
+
+	return z.accessToken, nil
+}
+
+// invalidateToken forgets the current access token in memory and on disk
+func (z *Auth) invalidateToken() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.accessToken = ""
+	z.tokenExpiry = time.Time{}
+	if z.cache != nil {
+		_ = z.cache.Clear()
+	}
+}
+
+// GetAPIEndpoint returns the Zoho Creator API endpoint for form submission
+func (z *Auth) GetAPIEndpoint() string {
+	return fmt.Sprintf("https://creator.zoho.%s/api/v2/%s/%s/form/%s",
+		z.config.DataCenter,
+		z.config.AccountOwner,
+		z.config.AppLinkName,
+		z.config.FormLinkName,
+	)
+}
+
+// SubmitToZohoCreator submits survey data to Zoho Creator using OAuth.
+// Non-2xx responses are returned as *retry.StatusError.
+func (z *Auth) SubmitToZohoCreator(ctx context.Context, data map[string]interface{}, header http.Header) error {
+	// Get valid access token
+	accessToken, err := z.GetAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	// Prepare payload
+	payload := map[string]interface{}{
+		"data": data,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("failed to marshal data: %w", err))
+	}
+
+	// Create request
+	apiURL := z.GetAPIEndpoint()
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return retry.Permanent(fmt.Errorf("failed to create request: %w", err))
+	}
+
+	// Set headers
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Zoho-oauthtoken "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	// Make request
+	resp, err := z.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit to Zoho: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// A cached token can be revoked before it expires: drop it and let the
+	// caller retry with a fresh one instead of failing the submission for good
+	if resp.StatusCode == http.StatusUnauthorized {
+		z.invalidateToken()
+		return fmt.Errorf("Zoho API rejected the access token (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// Check status
+	if err := retry.CheckResponse(resp, body); err != nil {
+		return fmt.Errorf("Zoho API error: %w", err)
+	}
+
+	return nil
+}
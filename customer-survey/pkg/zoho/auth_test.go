@@ -0,0 +1,113 @@
+package zoho
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"customer-survey/pkg/retry"
+)
+
+func testAuth(t *testing.T, tokenURL, cachePath string) *Auth {
+	t.Helper()
+	a := NewAuth(&Config{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh", DataCenter: "eu"})
+	a.tokenBaseURL = tokenURL
+	a.SetTokenCache(NewTokenCache(cachePath))
+	return a
+}
+
+func TestAccessTokenIsCachedAcrossLaunches(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, calls)
+	}))
+	defer srv.Close()
+
+	cachePath := filepath.Join(t.TempDir(), TokenCacheFile)
+	token, err := testAuth(t, srv.URL, cachePath).GetAccessToken(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get access token: %v", err)
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("Token cache was not written: %v", err)
+	}
+	if bytes.Contains(data, []byte(token)) {
+		t.Error("Token cache should be encrypted")
+	}
+
+	// A new process with the same credentials reuses the cached token
+	again, err := testAuth(t, srv.URL, cachePath).GetAccessToken(context.Background())
+	if err != nil || again != token {
+		t.Errorf("Expected cached token %q, got %q (%v)", token, again, err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single refresh, got %d", calls)
+	}
+
+	// Rotating the refresh token invalidates the cache
+	rotated := testAuth(t, srv.URL, cachePath)
+	rotated.config.RefreshToken = "rotated"
+	if token, _ := rotated.GetAccessToken(context.Background()); token != "token-2" {
+		t.Errorf("Expected a fresh token after rotation, got %q", token)
+	}
+}
+
+func TestRevokedRefreshToken(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"error":"invalid_grant"}`)
+	}))
+	defer srv.Close()
+
+	cachePath := filepath.Join(t.TempDir(), TokenCacheFile)
+	_, err := testAuth(t, srv.URL, cachePath).GetAccessToken(context.Background())
+	if !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Fatalf("Expected ErrRefreshTokenRevoked, got %v", err)
+	}
+	if !retry.IsStopped(err) || retry.IsPermanent(err) {
+		t.Errorf("Revoked token should stop retries but keep submissions queued, got %v", err)
+	}
+
+	// Later launches remember the rejection instead of asking Zoho again
+	if _, err := testAuth(t, srv.URL, cachePath).GetAccessToken(context.Background()); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("Expected remembered rejection, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single call to the token endpoint, got %d", calls)
+	}
+}
+
+func TestRefreshFollowsContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("refresh_token") != "refresh" {
+			t.Errorf("Expected the refresh token in the form, got %q", r.FormValue("refresh_token"))
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	a := testAuth(t, srv.URL, filepath.Join(t.TempDir(), TokenCacheFile))
+	if _, err := a.GetAccessToken(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("A refresh should stop with its context, got %v", err)
+	}
+
+	// The caller's config is not changed
+	cfg := &Config{ClientID: "id"}
+	NewAuth(cfg)
+	if cfg.DataCenter != "" {
+		t.Errorf("NewAuth should not change the caller's config, got data center %q", cfg.DataCenter)
+	}
+}
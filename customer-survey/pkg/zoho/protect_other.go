@@ -0,0 +1,63 @@
+//go:build !windows
+
+package zoho
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// On platforms without DPAPI the cache is sealed with AES-GCM using a random
+// key kept next to it, readable only by the owning user.
+
+// protect encrypts data with the key stored beside path, creating it if needed
+func protect(data []byte, path string) ([]byte, error) {
+	gcm, err := cacheCipher(path, true)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// unprotect decrypts data produced by protect
+func unprotect(data []byte, path string) ([]byte, error) {
+	gcm, err := cacheCipher(path, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("token cache is truncated")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func cacheCipher(path string, create bool) (cipher.AEAD, error) {
+	keyPath := path + ".key"
+	key, err := os.ReadFile(keyPath)
+	if err != nil || len(key) != 32 {
+		if !create {
+			return nil, fmt.Errorf("token cache key unavailable: %v", err)
+		}
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write token cache key: %w", err)
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,43 @@
+//go:build windows
+
+package zoho
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+func newBlob(d []byte) *windows.DataBlob {
+	if len(d) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(d)), Data: &d[0]}
+}
+
+// blobBytes copies out a blob allocated by DPAPI and frees it
+func blobBytes(b *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(b.Data)))
+	out := make([]byte, b.Size)
+	copy(out, unsafe.Slice(b.Data, b.Size))
+	return out
+}
+
+// protect encrypts data with DPAPI for the current Windows user
+func protect(data []byte, _ string) ([]byte, error) {
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(newBlob(data), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, fmt.Errorf("CryptProtectData: %w", err)
+	}
+	return blobBytes(&out), nil
+}
+
+// unprotect decrypts data produced by protect for the same user
+func unprotect(data []byte, _ string) ([]byte, error) {
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(newBlob(data), nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, fmt.Errorf("CryptUnprotectData: %w", err)
+	}
+	return blobBytes(&out), nil
+}
@@ -0,0 +1,100 @@
+package zoho
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"customer-survey/pkg/appdata"
+)
+
+// TokenCacheFile is the name of the encrypted token cache in the per-user AppData folder
+const TokenCacheFile = "zoho_token.bin"
+
+// cachedToken is what the token cache stores, encrypted, on disk
+type cachedToken struct {
+	Fingerprint string    `json:"fingerprint"`
+	AccessToken string    `json:"access_token,omitempty"`
+	Expiry      time.Time `json:"expiry"`
+	Revoked     bool      `json:"revoked,omitempty"` // refresh token was rejected with invalid_grant
+}
+
+// TokenCache keeps the last access token between launches so that every
+// start does not spend a refresh against Zoho's rate-limited token endpoint.
+// The file is encrypted for the current user (DPAPI on Windows).
+type TokenCache struct {
+	path string
+}
+
+// NewTokenCache returns a token cache stored at path
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{path: path}
+}
+
+// DefaultTokenCache returns the cache in %APPDATA%\CustomerSurvey
+func DefaultTokenCache() *TokenCache {
+	return NewTokenCache(filepath.Join(appdata.Dir(), TokenCacheFile))
+}
+
+// Path returns the file backing the cache
+func (c *TokenCache) Path() string {
+	return c.path
+}
+
+// load returns the cached entry for the given credentials, or nil if there is
+// none. An unreadable or foreign entry is treated as a cache miss.
+func (c *TokenCache) load(fingerprint string) *cachedToken {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil
+	}
+	plain, err := unprotect(data, c.path)
+	if err != nil {
+		return nil
+	}
+	var tok cachedToken
+	if err := json.Unmarshal(plain, &tok); err != nil || tok.Fingerprint != fingerprint {
+		return nil
+	}
+	return &tok
+}
+
+// save encrypts tok and replaces the cache file
+func (c *TokenCache) save(tok *cachedToken) error {
+	plain, err := json.Marshal(tok)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+	data, err := protect(plain, c.path)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token cache: %w", err)
+	}
+
+	if err := appdata.WriteFileAtomic(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to replace token cache: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the cached token
+func (c *TokenCache) Clear() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// fingerprint identifies the credentials a cached token belongs to, so that
+// rotating the refresh token or moving data center invalidates the cache.
+// Only a hash is stored, never the secrets themselves.
+func (c *Config) fingerprint() string {
+	sum := sha256.Sum256([]byte(c.DataCenter + "\x00" + c.ClientID + "\x00" + c.RefreshToken))
+	return hex.EncodeToString(sum[:])
+}
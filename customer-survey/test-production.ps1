@@ -71,27 +71,28 @@ Write-Host "  Running with -reset flag..." -ForegroundColor Gray
 Start-Process -FilePath $exePath -ArgumentList "-reset" -Wait
 
 Write-Host "  Checking state after reset..." -ForegroundColor Gray
-if (Test-Path "$env:APPDATA\CustomerSurvey") {
-    $files = Get-ChildItem "$env:APPDATA\CustomerSurvey"
-    if ($files.Count -eq 0) {
-        Write-Host "  ✓ All flags removed (correct)" -ForegroundColor Green
+$statePath = "$env:APPDATA\CustomerSurvey\state.json"
+if (Test-Path $statePath) {
+    $state = (Get-Content $statePath -Raw | ConvertFrom-Json).survey
+    if (-not ($state.done_at -or $state.no_thanks_at -or $state.remind_at)) {
+        Write-Host "  ✓ All choices cleared (correct)" -ForegroundColor Green
     } else {
-        Write-Host "  Files still exist:" -ForegroundColor Yellow
-        $files | Format-Table Name, LastWriteTime
+        Write-Host "  Choices still recorded:" -ForegroundColor Yellow
+        $state | Format-List
     }
 } else {
-    Write-Host "  ✓ Folder removed or empty (correct)" -ForegroundColor Green
+    Write-Host "  ✓ No state recorded (correct)" -ForegroundColor Green
 }
 
 Read-Host "`nPress Enter to continue to Test 4"
 
 # Test 4: Remind Me Later Simulation
 Write-Host "`n[Test 4] Remind Me Later Simulation" -ForegroundColor Yellow
-Write-Host "  Creating remind.txt with future date..." -ForegroundColor Gray
+Write-Host "  Writing state.json with a future reminder..." -ForegroundColor Gray
 
 New-Item -Path "$env:APPDATA\CustomerSurvey" -ItemType Directory -Force | Out-Null
 $futureDate = (Get-Date).AddDays(7).ToString("o")
-Set-Content "$env:APPDATA\CustomerSurvey\remind.txt" -Value $futureDate
+@{ version = 1; survey = @{ remind_at = $futureDate } } | ConvertTo-Json | Set-Content "$env:APPDATA\CustomerSurvey\state.json"
 
 Write-Host "  Remind date set to: $futureDate" -ForegroundColor Cyan
 
@@ -111,10 +112,10 @@ Read-Host "`nPress Enter to continue to Test 5"
 
 # Test 5: Expired Reminder
 Write-Host "`n[Test 5] Expired Reminder Simulation" -ForegroundColor Yellow
-Write-Host "  Creating remind.txt with PAST date..." -ForegroundColor Gray
+Write-Host "  Writing state.json with a PAST reminder..." -ForegroundColor Gray
 
 $pastDate = (Get-Date).AddDays(-1).ToString("o")
-Set-Content "$env:APPDATA\CustomerSurvey\remind.txt" -Value $pastDate
+@{ version = 1; survey = @{ remind_at = $pastDate } } | ConvertTo-Json | Set-Content "$env:APPDATA\CustomerSurvey\state.json"
 
 Write-Host "  Remind date set to: $pastDate (expired)" -ForegroundColor Cyan
 
@@ -123,8 +124,8 @@ Write-Host "  Expected: Should show UI (reminder expired)" -ForegroundColor Yell
 
 Start-Process -FilePath $exePath -Wait
 
-Write-Host "`n  Check if new flag was created..." -ForegroundColor Gray
-Get-ChildItem "$env:APPDATA\CustomerSurvey" | Format-Table Name, LastWriteTime
+Write-Host "`n  Check if a new choice was recorded..." -ForegroundColor Gray
+Get-Content "$env:APPDATA\CustomerSurvey\state.json"
 
 Read-Host "`nPress Enter to see final summary"
 